@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Action is a single value of the `action` label; every action lives in its own action_*.go file and is added to registeredActions
+type Action interface {
+	// Name is the value of the `action` label selecting this action
+	Name() string
+	// Description is a one line explanation shown in the help
+	Description() string
+	// Labels returns the labels this action reads, besides the common ones
+	Labels() []string
+	// Validate checks the config before anything is executed
+	Validate(cfg Config) error
+	// Run executes the commands of the action through the runner
+	Run(ctx context.Context, runner CommandRunner, cfg Config) error
+}
+
+// the labels used by almost all actions, so they're not repeated for every action in the help
+var commonLabels = []string{"workingDirectory", "allSolutions", "parallelism", "solution", "configuration", "buildVersion", "forceRestore", "forceBuild", "dryRun", "reportPath"}
+
+var registeredActions = []Action{
+	&restoreAction{},
+	&buildAction{},
+	&testAction{name: "test", description: "Runs the tests for every test project in the solution."},
+	&testAction{name: "unit-test", description: "Runs the unit tests of the test projects in the solution.", testType: unitTests},
+	&testAction{name: "integration-test", description: "Runs the integration tests of the test projects in the solution.", testType: integrationTests},
+	&analyzeSonarQubeAction{},
+	&publishAction{},
+	&packAction{},
+	&pushNugetAction{},
+	&ciAction{},
+}
+
+// Returns the registered action with the specified name, or nil if there's no such action.
+func getAction(name string) Action {
+	for _, a := range registeredActions {
+		if a.Name() == name {
+			return a
+		}
+	}
+
+	return nil
+}
+
+func getActionNames() []string {
+	names := make([]string, 0, len(registeredActions))
+	for _, a := range registeredActions {
+		names = append(names, a.Name())
+	}
+
+	return names
+}
+
+// Runs the action set in the config, executing all commands through the runner.
+func runAction(ctx context.Context, runner CommandRunner, cfg Config) error {
+	if cfg.AllSolutions {
+		return runActionForAllSolutions(ctx, runner, cfg)
+	}
+
+	return runActionForSolution(ctx, runner, cfg)
+}
+
+// Runs the action set in the config for the solution in the config.
+func runActionForSolution(ctx context.Context, runner CommandRunner, cfg Config) error {
+	a := getAction(cfg.Action)
+	if a == nil {
+		return fmt.Errorf("set `action: <action>` on this step to any of %v", strings.Join(getActionNames(), ", "))
+	}
+
+	err := a.Validate(cfg)
+	if err != nil {
+		return fmt.Errorf("invalid configuration for action %v: %w", a.Name(), err)
+	}
+
+	if cfg.Report != nil {
+		runner = &reportingRunner{runner: runner, report: cfg.Report}
+	}
+
+	return a.Run(ctx, runner, cfg)
+}
+
+// Returns the commands the action would execute, by running it against a runner which only records them.
+// The solutions are planned one after the other, so the commands of every solution stay together in the order of the solutions.
+func planAction(ctx context.Context, cfg Config) ([]Command, error) {
+	cfg.Parallelism = 1
+	runner := &FakeCommandRunner{}
+	err := runAction(ctx, runner, cfg)
+
+	return runner.Commands, err
+}
+
+// Returns the help text listing all actions and the labels they support.
+func actionsHelp() string {
+	var sb strings.Builder
+
+	sb.WriteString("Builds, tests, packs and publishes .NET applications and libraries.\n\nActions:\n")
+	for _, a := range registeredActions {
+		labels := append([]string{}, a.Labels()...)
+		sort.Strings(labels)
+
+		fmt.Fprintf(&sb, "  %v: %v\n", a.Name(), a.Description())
+		if len(labels) > 0 {
+			fmt.Fprintf(&sb, "    labels: %v\n", strings.Join(labels, ", "))
+		}
+	}
+	fmt.Fprintf(&sb, "\nCommon labels: %v", strings.Join(commonLabels, ", "))
+
+	return sb.String()
+}
+
+// Appends the flags skipping the implicit restore and build, unless they're forced.
+func appendSkipFlags(args []string, cfg Config, skipBuild bool) []string {
+	if !cfg.ForceRestore {
+		args = append(args, "--no-restore")
+	}
+
+	if skipBuild && !cfg.ForceBuild {
+		args = append(args, "--no-build")
+	}
+
+	return args
+}
+
+// implicitRestore holds what a command needs for the restore dotnet does implicitly when forceRestore is set
+type implicitRestore struct {
+	// args point the restore to the temporary NuGet config with the sources of the credentials
+	args []string
+	// env holds the NuGet credentials of the sources, with the secrets to mask in them
+	env     []string
+	secrets []string
+	// cleanup removes the temporary NuGet config again
+	cleanup func()
+}
+
+// Prepares the restore dotnet does implicitly when forceRestore is set, with the same temporary NuGet config as the restore action, so it finds and authenticates the same sources.
+// Without forceRestore nothing is restored, so the returned implicit restore is empty.
+func prepareImplicitRestore(ctx context.Context, runner CommandRunner, cfg Config) (*implicitRestore, error) {
+	if !cfg.ForceRestore {
+		return &implicitRestore{cleanup: func() {}}, nil
+	}
+
+	credentials, err := resolveRestoreNugetServerCredentials(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	nugetConfig, err := prepareRestoreNugetConfig(ctx, runner, cfg, credentials, "")
+	if err != nil {
+		return nil, err
+	}
+
+	restore := &implicitRestore{cleanup: nugetConfig.cleanup}
+	if nugetConfig.path != "" {
+		restore.args = []string{fmt.Sprintf("/p:RestoreConfigFile=%s", nugetConfig.path)}
+	}
+	restore.env, restore.secrets = getNugetCredentialsEnv(nugetConfig.sources)
+
+	return restore, nil
+}
+
+// Appends the selected solution, so dotnet doesn't have to pick one itself when the directory contains several.
+func appendSolutionArg(args []string, cfg Config) []string {
+	if cfg.Solution != nil {
+		args = append(args, cfg.Solution.getRelativePath(cfg.WorkingDirectory))
+	}
+
+	return args
+}
+
+func appendVersionFlag(args []string, cfg Config) []string {
+	if cfg.BuildVersion != "" {
+		args = append(args, fmt.Sprintf("/p:Version=%s", cfg.BuildVersion))
+	}
+
+	return args
+}
+
+func validateConfiguration(cfg Config) error {
+	if cfg.Configuration == "" {
+		return fmt.Errorf("the configuration label can't be empty")
+	}
+
+	return nil
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+
+	"github.com/rs/zerolog/log"
+)
+
+type buildAction struct{}
+
+func (a *buildAction) Name() string {
+	return "build"
+}
+
+func (a *buildAction) Description() string {
+	return "Builds the solution with dotnet build."
+}
+
+func (a *buildAction) Labels() []string {
+	return nil
+}
+
+func (a *buildAction) Validate(cfg Config) error {
+	return validateConfiguration(cfg)
+}
+
+func (a *buildAction) Run(ctx context.Context, runner CommandRunner, cfg Config) error {
+
+	// Minimal example with defaults.
+	// image: extensions/dotnet:stable
+	// action: build
+
+	// Customizations.
+	// image: extensions/dotnet:stable
+	// action: build
+	// configuration: Debug
+	// versionSuffix: 5
+
+	log.Printf("Building the solution...\n")
+
+	args := appendSolutionArg([]string{"build"}, cfg)
+	args = append(args,
+		"--configuration",
+		cfg.Configuration,
+		"/p:IncludeSourceRevisionInInformationalVersion=false",
+	)
+
+	args = appendVersionFlag(args, cfg)
+	args = appendSkipFlags(args, cfg, false)
+
+	restore, err := prepareImplicitRestore(ctx, runner, cfg)
+	if err != nil {
+		return err
+	}
+	defer restore.cleanup()
+	args = append(args, restore.args...)
+
+	return runner.Run(ctx, Command{Name: "dotnet", Args: args, Env: restore.env, Dir: cfg.WorkingDirectory, Secrets: restore.secrets})
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBuild(t *testing.T) {
+	cfg := newTestConfig(t, "webservice")
+	cfg.Action = "build"
+	cfg.BuildVersion = "1.2.3"
+	runner := &FakeCommandRunner{}
+
+	err := runAction(context.Background(), runner, cfg)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertCommands(t, runner, "dotnet build Acme.FooApi.sln --configuration Release /p:IncludeSourceRevisionInInformationalVersion=false /p:Version=1.2.3 --no-restore")
+}
+
+func TestBuildSelectedSolution(t *testing.T) {
+	cfg := newTestConfig(t, "webservice")
+	cfg.Action = "build"
+	cfg.WorkingDirectory, _ = filepath.Abs("testdata/multi")
+	cfg.Solution, _ = findSolution(cfg.WorkingDirectory, "Acme.App.Tools")
+	runner := &FakeCommandRunner{}
+
+	err := runAction(context.Background(), runner, cfg)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertCommands(t, runner, "dotnet build Acme.App.Tools.slnx --configuration Release /p:IncludeSourceRevisionInInformationalVersion=false --no-restore")
+}
+
+func TestBuildWithForceRestore(t *testing.T) {
+
+	t.Run("RestoresFromSourcesOfCredentials", func(t *testing.T) {
+		cfg := newTestConfig(t, "webservice")
+		cfg.Action = "build"
+		cfg.ForceRestore = true
+		cfg.NugetServerCredentialsJSONPath, _ = filepath.Abs("testdata/credentials/nuget_server.json")
+		var configContent string
+		runner := &FakeCommandRunner{
+			RunFunc: func(command Command) error {
+				if command.Args[0] == "build" {
+					content, err := os.ReadFile(strings.TrimPrefix(command.Args[len(command.Args)-1], "/p:RestoreConfigFile="))
+					if err != nil {
+						return err
+					}
+					configContent = string(content)
+				}
+				return nil
+			},
+		}
+
+		err := runAction(context.Background(), runner, cfg)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+		configPath := runner.Commands[0].Args[len(runner.Commands[0].Args)-1]
+		assertCommands(t, runner,
+			"dotnet nuget add source --name github-nuget https://nuget.pkg.github.com/acme/index.json --configfile "+configPath,
+			"NuGetPackageSourceCredentials_github-nuget=******** VSS_NUGET_EXTERNAL_FEED_ENDPOINTS=******** dotnet build Acme.FooApi.sln --configuration Release /p:IncludeSourceRevisionInInformationalVersion=false /p:RestoreConfigFile="+configPath)
+		if runner.Commands[1].Env[0] != "NuGetPackageSourceCredentials_github-nuget=Username=acme-bot;Password=github-secret-key" {
+			t.Errorf("expected the credentials of the restore source, got %v", runner.Commands[1].Env[0])
+		}
+		if !strings.Contains(configContent, "nuget.org") {
+			t.Errorf("expected the build to restore with a copy of the default nuget config, got:\n%v", configContent)
+		}
+		if _, err := os.Stat(configPath); !os.IsNotExist(err) {
+			t.Errorf("expected the temporary nuget config to be removed, got %v", err)
+		}
+	})
+
+	t.Run("UsesPackageSourceMapping", func(t *testing.T) {
+		cfg := newTestConfig(t, "webservice")
+		cfg.Action = "build"
+		cfg.ForceRestore = true
+		cfg.NugetServerCredentialsJSONPath, _ = filepath.Abs("testdata/credentials/nuget_server.json")
+		cfg.PackageSourceMapping = `{"nuget.org": ["*"], "github-nuget": ["Acme.*"]}`
+		var configContent string
+		runner := &FakeCommandRunner{
+			RunFunc: func(command Command) error {
+				if command.Args[0] == "build" {
+					content, err := os.ReadFile(strings.TrimPrefix(command.Args[len(command.Args)-1], "/p:RestoreConfigFile="))
+					if err != nil {
+						return err
+					}
+					configContent = string(content)
+				}
+				return nil
+			},
+		}
+
+		err := runAction(context.Background(), runner, cfg)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(configContent, `<packageSource key="github-nuget">`) {
+			t.Errorf("expected the package source mapping in the nuget config of the build, got:\n%v", configContent)
+		}
+	})
+
+	t.Run("WithoutCredentialsUsesNugetConfigAsItIs", func(t *testing.T) {
+		cfg := newTestConfig(t, "webservice")
+		cfg.Action = "build"
+		cfg.ForceRestore = true
+		runner := &FakeCommandRunner{}
+
+		err := runAction(context.Background(), runner, cfg)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertCommands(t, runner, "dotnet build Acme.FooApi.sln --configuration Release /p:IncludeSourceRevisionInInformationalVersion=false")
+	})
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	foundation "github.com/estafette/estafette-foundation"
+	"github.com/rs/zerolog/log"
+)
+
+// ciAction runs several actions in sequence in a single stage, so the container startup and package restore are only paid once
+type ciAction struct{}
+
+type stepResult struct {
+	name     string
+	status   string
+	duration time.Duration
+	err      error
+}
+
+func (a *ciAction) Name() string {
+	return "ci"
+}
+
+func (a *ciAction) Description() string {
+	return "Runs the actions listed in steps in sequence, stopping at the first failing step."
+}
+
+func (a *ciAction) Labels() []string {
+	labels := []string{"steps"}
+	for _, name := range getActionNames() {
+		if sub := getAction(name); sub != a {
+			labels = append(labels, sub.Labels()...)
+		}
+	}
+
+	return dedupe(labels)
+}
+
+func (a *ciAction) Validate(cfg Config) error {
+	if len(cfg.Steps) == 0 {
+		return fmt.Errorf("the steps label has to list the actions to run, for example [restore, build, unit-test, pack]")
+	}
+
+	for _, name := range cfg.Steps {
+		sub := getAction(name)
+		if sub == nil {
+			return fmt.Errorf("step %v is not any of %v", name, strings.Join(getActionNames(), ", "))
+		}
+		if sub == a {
+			return fmt.Errorf("the ci action can't be used as a step")
+		}
+		err := sub.Validate(cfg)
+		if err != nil {
+			return fmt.Errorf("step %v: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+func (a *ciAction) Run(ctx context.Context, runner CommandRunner, cfg Config) error {
+
+	// Minimal example.
+	// image: extensions/dotnet:stable
+	// action: ci
+	// steps:
+	// - restore
+	// - build
+	// - unit-test
+	// - pack
+
+	// Resolve the NuGet credentials once, so all steps use the same server; with nugetServerNames the servers are already named explicitly.
+	// Steps which don't use the credentials shouldn't fail on them, like they don't when running as separate stages.
+	if len(cfg.NugetServerNames) == 0 && usesNugetCredentials(cfg) {
+		credential, err := resolveNugetServerCredentials(cfg, cfg.NugetServerName)
+		if err != nil {
+			return err
+		}
+		if credential != nil {
+			cfg.NugetServerURL, cfg.NugetServerAPIKey = credential.AdditionalProperties.APIURL, credential.AdditionalProperties.APIKey
+			cfg.NugetServerUsername, cfg.NugetSourceName = credential.getUsername(), credential.getSourceName()
+		}
+	}
+
+	results := make([]stepResult, 0, len(cfg.Steps))
+	var failure error
+	for _, name := range cfg.Steps {
+		if failure != nil {
+			results = append(results, stepResult{name: name, status: "skipped"})
+			continue
+		}
+
+		log.Info().Msgf("Running step %v...", name)
+
+		start := time.Now()
+		err := getAction(name).Run(ctx, runner, cfg)
+		result := stepResult{name: name, status: "succeeded", duration: time.Since(start), err: err}
+		if err != nil {
+			result.status = "failed"
+			failure = fmt.Errorf("step %v failed: %w", name, err)
+		}
+
+		results = append(results, result)
+	}
+
+	log.Info().Msg(formatStepResults("Summary of the ci steps:", results))
+
+	return failure
+}
+
+// Returns whether any of the steps uses the NuGet credentials, for restoring or pushing packages.
+func usesNugetCredentials(cfg Config) bool {
+	return cfg.ForceRestore || foundation.StringArrayContains(cfg.Steps, "restore") || foundation.StringArrayContains(cfg.Steps, "push-nuget")
+}
+
+// Returns a summary with the status and duration of every step.
+func formatStepResults(title string, results []stepResult) string {
+	var sb strings.Builder
+
+	width := 20
+	for _, r := range results {
+		if len(r.name)+1 > width {
+			width = len(r.name) + 1
+		}
+	}
+
+	sb.WriteString(title)
+	for _, r := range results {
+		fmt.Fprintf(&sb, "\n  %-*v %-10v %v", width, r.name, r.status, r.duration.Round(time.Millisecond))
+	}
+
+	return sb.String()
+}
+
+func dedupe(items []string) []string {
+	seen := map[string]bool{}
+	deduped := []string{}
+	for _, item := range items {
+		if !seen[item] {
+			seen[item] = true
+			deduped = append(deduped, item)
+		}
+	}
+
+	return deduped
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCI(t *testing.T) {
+
+	t.Run("RunsStepsInOrderWithSharedCredentials", func(t *testing.T) {
+		cfg := newTestConfig(t, "webservice")
+		cfg.Action = "ci"
+		cfg.Steps = []string{"restore", "build", "unit-test"}
+		cfg.NugetServerCredentialsJSONPath, _ = filepath.Abs("testdata/credentials/nuget_server.json")
+		runner := &FakeCommandRunner{}
+
+		err := runAction(context.Background(), runner, cfg)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+		configPath := runner.Commands[0].Args[len(runner.Commands[0].Args)-1]
+		assertCommands(t, runner,
+			"dotnet nuget add source --name github-nuget https://nuget.pkg.github.com/acme/index.json --configfile "+configPath,
+			"NuGetPackageSourceCredentials_github-nuget=******** VSS_NUGET_EXTERNAL_FEED_ENDPOINTS=******** dotnet restore Acme.FooApi.sln --packages .nuget/packages --configfile "+configPath,
+			"dotnet build Acme.FooApi.sln --configuration Release /p:IncludeSourceRevisionInInformationalVersion=false --no-restore",
+			"dotnet test --configuration Release --no-restore --no-build test/Acme.FooApi.UnitTests/Acme.FooApi.UnitTests.csproj")
+	})
+
+	t.Run("ForceRestoreUsesSameSourcesAsRestore", func(t *testing.T) {
+		cfg := newTestConfig(t, "webservice")
+		cfg.Action = "ci"
+		cfg.Steps = []string{"restore", "build"}
+		cfg.ForceRestore = true
+		cfg.NugetServerCredentialsJSONPath, _ = filepath.Abs("testdata/credentials/nuget_server.json")
+		runner := &FakeCommandRunner{}
+
+		err := runAction(context.Background(), runner, cfg)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+		restoreConfigPath := runner.Commands[0].Args[len(runner.Commands[0].Args)-1]
+		buildConfigPath := runner.Commands[2].Args[len(runner.Commands[2].Args)-1]
+		assertCommands(t, runner,
+			"dotnet nuget add source --name github-nuget https://nuget.pkg.github.com/acme/index.json --configfile "+restoreConfigPath,
+			"NuGetPackageSourceCredentials_github-nuget=******** VSS_NUGET_EXTERNAL_FEED_ENDPOINTS=******** dotnet restore Acme.FooApi.sln --packages .nuget/packages --configfile "+restoreConfigPath,
+			"dotnet nuget add source --name github-nuget https://nuget.pkg.github.com/acme/index.json --configfile "+buildConfigPath,
+			"NuGetPackageSourceCredentials_github-nuget=******** VSS_NUGET_EXTERNAL_FEED_ENDPOINTS=******** dotnet build Acme.FooApi.sln --configuration Release /p:IncludeSourceRevisionInInformationalVersion=false /p:RestoreConfigFile="+buildConfigPath)
+	})
+
+	t.Run("IgnoresCredentialsWhenNoStepUsesThem", func(t *testing.T) {
+		cfg := newTestConfig(t, "webservice")
+		cfg.Action = "ci"
+		cfg.Steps = []string{"build"}
+		cfg.NugetServerCredentialsJSONPath, _ = filepath.Abs("testdata/credentials/nuget_server.json")
+		cfg.NugetServerName = "unknown-nuget"
+		runner := &FakeCommandRunner{}
+
+		err := runAction(context.Background(), runner, cfg)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertCommands(t, runner,
+			"dotnet build Acme.FooApi.sln --configuration Release /p:IncludeSourceRevisionInInformationalVersion=false --no-restore")
+	})
+
+	t.Run("StopsAtFirstFailingStep", func(t *testing.T) {
+		cfg := newTestConfig(t, "webservice")
+		cfg.Action = "ci"
+		cfg.Steps = []string{"build", "unit-test", "publish"}
+		runner := &FakeCommandRunner{
+			RunFunc: func(command Command) error {
+				if command.Args[0] == "test" {
+					return errors.New("exit status 1")
+				}
+				return nil
+			},
+		}
+
+		err := runAction(context.Background(), runner, cfg)
+
+		if err == nil || !strings.Contains(err.Error(), "step unit-test failed") {
+			t.Fatalf("expected the unit-test step to fail, got %v", err)
+		}
+		if len(runner.Commands) != 2 {
+			t.Errorf("expected publish not to run, got %q", commandLines(runner))
+		}
+	})
+
+	t.Run("FailsOnUnknownOrNestedSteps", func(t *testing.T) {
+		for _, steps := range [][]string{nil, {"restore", "deploy"}, {"build", "ci"}} {
+			cfg := newTestConfig(t, "webservice")
+			cfg.Action = "ci"
+			cfg.Steps = steps
+			runner := &FakeCommandRunner{}
+
+			err := runAction(context.Background(), runner, cfg)
+
+			if err == nil {
+				t.Errorf("expected an error for steps %v", steps)
+			}
+			assertCommands(t, runner)
+		}
+	})
+}
+
+func TestFormatStepResults(t *testing.T) {
+	results := []stepResult{
+		{name: "restore", status: "succeeded", duration: 1500 * time.Millisecond},
+		{name: "build", status: "failed", duration: 2 * time.Second, err: errors.New("exit status 1")},
+		{name: "pack", status: "skipped"},
+	}
+
+	summary := formatStepResults("Summary of the ci steps:", results)
+
+	expected := "Summary of the ci steps:\n" +
+		"  restore              succeeded  1.5s\n" +
+		"  build                failed     2s\n" +
+		"  pack                 skipped    0s"
+	if summary != expected {
+		t.Errorf("unexpected summary\nexpected:\n%v\nactual:\n%v", expected, summary)
+	}
+}
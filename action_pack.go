@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+type packAction struct{}
+
+// the values of the symbolPackageFormat label, the formats of the symbol packages dotnet pack can create
+const (
+	symbolPackageFormatSnupkg  = "snupkg"
+	symbolPackageFormatSymbols = "symbols.nupkg"
+)
+
+func (a *packAction) Name() string {
+	return "pack"
+}
+
+func (a *packAction) Description() string {
+	return "Creates the NuGet package(s) with dotnet pack."
+}
+
+func (a *packAction) Labels() []string {
+	return []string{"includeSymbols", "symbolPackageFormat"}
+}
+
+func (a *packAction) Validate(cfg Config) error {
+	if cfg.IncludeSymbols {
+		switch cfg.SymbolPackageFormat {
+		case "", symbolPackageFormatSnupkg, symbolPackageFormatSymbols:
+		default:
+			return fmt.Errorf("the symbolPackageFormat label has to be %v or %v, not %v", symbolPackageFormatSnupkg, symbolPackageFormatSymbols, cfg.SymbolPackageFormat)
+		}
+	}
+
+	return validateConfiguration(cfg)
+}
+
+func (a *packAction) Run(ctx context.Context, runner CommandRunner, cfg Config) error {
+
+	// Minimal example with defaults.
+	// image: extensions/dotnet:stable
+	// action: pack
+
+	// Customizations.
+	// image: extensions/dotnet:stable
+	// action: pack
+	// force-restore: true
+	// force-build: true
+	// configuration: Debug
+	// versionSuffix: 5
+
+	// Symbol packages.
+	// image: extensions/dotnet:stable
+	// action: pack
+	// includeSymbols: true
+	// symbolPackageFormat: snupkg
+
+	log.Printf("Packing the nuget package(s)...\n")
+
+	args := []string{
+		"pack",
+		"--configuration",
+		cfg.Configuration,
+	}
+
+	args = appendVersionFlag(args, cfg)
+
+	if cfg.IncludeSymbols {
+		format := cfg.SymbolPackageFormat
+		if format == "" {
+			format = symbolPackageFormatSnupkg
+		}
+		args = append(args, "--include-symbols", fmt.Sprintf("/p:SymbolPackageFormat=%s", format))
+	}
+
+	args = appendSkipFlags(args, cfg, true)
+
+	restore, err := prepareImplicitRestore(ctx, runner, cfg)
+	if err != nil {
+		return err
+	}
+	defer restore.cleanup()
+	args = append(args, restore.args...)
+
+	// Without a solution file we leave it to dotnet to find the project in the working directory.
+	if cfg.Solution == nil {
+		return runner.Run(ctx, Command{Name: "dotnet", Args: args, Env: restore.env, Dir: cfg.WorkingDirectory, Secrets: restore.secrets})
+	}
+
+	projects, err := getPackProjects(cfg.Solution)
+	if err != nil {
+		return err
+	}
+
+	for _, p := range projects {
+		projectPath := cfg.Solution.getProjectPath(p, cfg.WorkingDirectory)
+		log.Printf("Packing %s...\n", projectPath)
+
+		argsForProject := make([]string, 0, len(args)+1)
+		argsForProject = append(argsForProject, args...)
+		argsForProject = append(argsForProject, projectPath)
+
+		start := time.Now()
+		err := runner.Run(ctx, Command{Name: "dotnet", Args: argsForProject, Env: restore.env, Dir: cfg.WorkingDirectory, Secrets: restore.secrets})
+		if err != nil {
+			return err
+		}
+
+		packages, err := findCreatedPackages(filepath.Join(cfg.WorkingDirectory, filepath.FromSlash(path.Dir(projectPath)), "bin", cfg.Configuration), start)
+		if err != nil {
+			return err
+		}
+		for _, p := range packages {
+			cfg.Report.AddArtifact(relativePath(cfg.WorkingDirectory, p))
+		}
+	}
+
+	return nil
+}
+
+// Returns the .nupkg and .snupkg files in the output directory of a project which were written since the pack started.
+func findCreatedPackages(dir string, since time.Time) ([]string, error) {
+	files, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed reading the packages in %v: %w", dir, err)
+	}
+
+	var packages []string
+	for _, f := range files {
+		if f.IsDir() || (filepath.Ext(f.Name()) != ".nupkg" && filepath.Ext(f.Name()) != ".snupkg") {
+			continue
+		}
+		info, err := f.Info()
+		if err != nil {
+			return nil, err
+		}
+		if !info.ModTime().Before(since.Truncate(time.Second)) {
+			packages = append(packages, filepath.Join(dir, f.Name()))
+		}
+	}
+
+	return packages, nil
+}
+
+// Returns the projects of the solution to pack, which are all projects except for the test projects and the ones which aren't packable.
+func getPackProjects(solution *Solution) ([]SolutionProject, error) {
+	var projects []SolutionProject
+	for _, p := range solution.getProjects() {
+		projectFile, err := solution.readProjectFile(p)
+		if err != nil {
+			return nil, err
+		}
+		if projectFile == nil || projectFile.IsTestProject() || strings.EqualFold(projectFile.Properties["IsPackable"], "false") {
+			continue
+		}
+
+		projects = append(projects, p)
+	}
+
+	return projects, nil
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestPack(t *testing.T) {
+	cfg := newTestConfig(t, "library")
+	cfg.Action = "pack"
+	cfg.BuildVersion = "1.0.0"
+	cfg.ForceBuild = true
+	runner := &FakeCommandRunner{}
+
+	err := runAction(context.Background(), runner, cfg)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertCommands(t, runner, "dotnet pack --configuration Release /p:Version=1.0.0 --no-restore src/Acme.Lib/Acme.Lib.csproj")
+}
+
+func TestPackSkipsTestProjects(t *testing.T) {
+	cfg := newTestConfig(t, "nested")
+	cfg.Action = "pack"
+	runner := &FakeCommandRunner{}
+
+	err := runAction(context.Background(), runner, cfg)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertCommands(t, runner,
+		"dotnet pack --configuration Release --no-restore --no-build Acme.Shop/Acme.Shop.csproj",
+		"dotnet pack --configuration Release --no-restore --no-build services/Acme.Shop.Api/Acme.Shop.Api.csproj")
+}
+
+func TestPackIncludesSymbols(t *testing.T) {
+	cfg := newTestConfig(t, "library")
+	cfg.Action = "pack"
+	cfg.IncludeSymbols = true
+	cfg.SymbolPackageFormat = "snupkg"
+	runner := &FakeCommandRunner{}
+
+	err := runAction(context.Background(), runner, cfg)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertCommands(t, runner, "dotnet pack --configuration Release --include-symbols /p:SymbolPackageFormat=snupkg --no-restore --no-build src/Acme.Lib/Acme.Lib.csproj")
+
+	cfg.SymbolPackageFormat = "pdb"
+	err = runAction(context.Background(), &FakeCommandRunner{}, cfg)
+
+	if err == nil {
+		t.Fatal("expected an error for an unknown symbol package format")
+	}
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+
+	foundation "github.com/estafette/estafette-foundation"
+	"github.com/rs/zerolog/log"
+)
+
+type publishAction struct{}
+
+// the runtime identifier to publish for when the runtimeId label isn't set
+const defaultRuntimeID = "linux-x64"
+
+func (a *publishAction) Name() string {
+	return "publish"
+}
+
+func (a *publishAction) Description() string {
+	return "Publishes the self-contained binaries of the project with dotnet publish."
+}
+
+func (a *publishAction) Labels() []string {
+	return []string{"project", "runtimeId", "outputFolder", "publishReadyToRun", "publishSingleFile", "publishTrimmed"}
+}
+
+func (a *publishAction) Validate(cfg Config) error {
+	if len(cfg.RuntimeIDs) > 1 {
+		return fmt.Errorf("the publish action publishes for a single runtime, but the runtimeId label lists %v", strings.Join(cfg.RuntimeIDs, ", "))
+	}
+
+	return validateConfiguration(cfg)
+}
+
+func (a *publishAction) Run(ctx context.Context, runner CommandRunner, cfg Config) error {
+
+	// Minimal example with defaults.
+	// image: extensions/dotnet:stable
+	// action: publish
+
+	// Customizations.
+	// image: extensions/dotnet:stable
+	// action: publish
+	// project: src/CustomProject
+	// configuration: Debug
+	// runtimteId: windows10-x64
+	// outputFolder: ./binaries
+	// buildVersion: 1.5.0
+	// forceRestore: true
+
+	log.Printf("Publishing the binaries...\n")
+
+	// The solution is called Acme.FooApi, then we by default look for a project called Acme.FooApi.WebService, and if that doesn't exist, we fall back to simply Acme.FooApi
+	if cfg.Project == "" {
+		var err error
+		cfg.Project, err = detectPublishProject(cfg)
+		if err != nil {
+			return err
+		}
+		log.Printf("Detected project %v to publish.\n", cfg.Project)
+	}
+
+	if cfg.OutputFolder == "" {
+		// A default sensible choice is to put the publishing output directly under the working folder in a folder called "publish", so that its relative path doesn't depend on the project name.
+		// This makes it easier to use in a generic way in followup steps of the build.
+		cfg.OutputFolder = filepath.Join(cfg.WorkingDirectory, "publish")
+	}
+
+	runtimeID := defaultRuntimeID
+	if len(cfg.RuntimeIDs) > 0 {
+		runtimeID = cfg.RuntimeIDs[0]
+	}
+
+	cfg.Report.SetValue("publishProject", cfg.Project)
+	cfg.Report.SetValue("outputFolder", cfg.OutputFolder)
+	cfg.Report.SetValue("runtimeId", runtimeID)
+
+	// Publishing without restoring needs the runtime specific assets, which a restore without the runtime identifier doesn't have.
+	if !cfg.ForceRestore {
+		err := checkRestoredForRuntime(cfg, runtimeID)
+		if err != nil {
+			return err
+		}
+	}
+
+	args := []string{
+		"publish",
+		"--configuration",
+		cfg.Configuration,
+		"--runtime",
+		runtimeID,
+		"--self-contained",
+		"true",
+		"--output",
+		cfg.OutputFolder,
+		cfg.Project,
+		"/p:IncludeSourceRevisionInInformationalVersion=false",
+	}
+
+	args = appendVersionFlag(args, cfg)
+
+	if cfg.PublishReadyToRun {
+		args = append(args, "/p:PublishReadyToRun=true", "/p:PublishReadyToRunShowWarnings=true")
+	}
+	if cfg.PublishSingleFile {
+		args = append(args, "/p:PublishSingleFile=true")
+	}
+	if cfg.PublishTrimmed {
+		args = append(args, "/p:PublishTrimmed=true")
+	}
+
+	args = appendSkipFlags(args, cfg, false)
+
+	restore, err := prepareImplicitRestore(ctx, runner, cfg)
+	if err != nil {
+		return err
+	}
+	defer restore.cleanup()
+	args = append(args, restore.args...)
+
+	err = runner.Run(ctx, Command{Name: "dotnet", Args: args, Env: restore.env, Dir: cfg.WorkingDirectory, Secrets: restore.secrets})
+	if err != nil {
+		return err
+	}
+
+	cfg.Report.AddArtifact(cfg.OutputFolder)
+
+	return nil
+}
+
+// Returns an error explaining how to fix the restore if the project to publish was restored, but not for the runtime identifier.
+// Without an assets file there's nothing to check, dotnet then fails with its own error that the project isn't restored.
+func checkRestoredForRuntime(cfg Config, runtimeID string) error {
+	projectPath := cfg.Project
+	if !filepath.IsAbs(projectPath) {
+		projectPath = filepath.Join(cfg.WorkingDirectory, filepath.FromSlash(projectPath))
+	}
+
+	assetsFilePath := getProjectAssetsFilePath(projectPath)
+	if !foundation.FileExists(assetsFilePath) {
+		return nil
+	}
+
+	assetsFile, err := ReadProjectAssetsFile(assetsFilePath)
+	if err != nil {
+		return err
+	}
+
+	missing := assetsFile.getFrameworksMissingRuntime(runtimeID)
+	if len(missing) > 0 {
+		return fmt.Errorf("project %v was restored for %v without runtime %v, which publishing without restoring needs (NETSDK1047); set runtimeId: %v on the restore action as well, or set forceRestore: true on the publish action", cfg.Project, strings.Join(missing, ", "), runtimeID, runtimeID)
+	}
+
+	return nil
+}
+
+// Returns the directory of the project to publish, looked up in the solution or in the ./src folder if there's no solution file.
+func detectPublishProject(cfg Config) (string, error) {
+	candidates := []string{cfg.SolutionName + ".WebService", cfg.SolutionName}
+
+	if cfg.Solution != nil {
+		for _, name := range candidates {
+			if p := cfg.Solution.getProjectByName(name); p != nil {
+				return path.Dir(cfg.Solution.getProjectPath(*p, cfg.WorkingDirectory)), nil
+			}
+		}
+
+		return "", fmt.Errorf("the project to be published can not be found, the solution %v doesn't contain a project named %v, please specify it with the 'project' label", cfg.SolutionName, strings.Join(candidates, " or "))
+	}
+
+	for _, name := range candidates {
+		project := fmt.Sprintf("src/%s", name)
+		if foundation.DirExists(filepath.Join(cfg.WorkingDirectory, project)) {
+			return project, nil
+		}
+	}
+
+	return "", fmt.Errorf("the project to be published can not be found at src/%s, please specify it with the 'project' label", strings.Join(candidates, " or src/"))
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPublish(t *testing.T) {
+
+	t.Run("DetectsWebServiceProject", func(t *testing.T) {
+		cfg := newTestConfig(t, "webservice")
+		cfg.Action = "publish"
+		runner := &FakeCommandRunner{}
+
+		err := runAction(context.Background(), runner, cfg)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertCommands(t, runner, "dotnet publish --configuration Release --runtime linux-x64 --self-contained true --output "+filepath.Join(cfg.WorkingDirectory, "publish")+" src/Acme.FooApi.WebService /p:IncludeSourceRevisionInInformationalVersion=false --no-restore")
+	})
+
+	t.Run("FallsBackToSolutionNamedProject", func(t *testing.T) {
+		cfg := newTestConfig(t, "library")
+		cfg.Action = "publish"
+		cfg.OutputFolder = "./binaries"
+		cfg.PublishSingleFile = true
+		runner := &FakeCommandRunner{}
+
+		err := runAction(context.Background(), runner, cfg)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertCommands(t, runner, "dotnet publish --configuration Release --runtime linux-x64 --self-contained true --output ./binaries src/Acme.Lib /p:IncludeSourceRevisionInInformationalVersion=false /p:PublishSingleFile=true --no-restore")
+	})
+
+	t.Run("DetectsProjectOutsideSrcFolder", func(t *testing.T) {
+		cfg := newTestConfig(t, "nested")
+		cfg.Action = "publish"
+		cfg.OutputFolder = "./binaries"
+		runner := &FakeCommandRunner{}
+
+		err := runAction(context.Background(), runner, cfg)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertCommands(t, runner, "dotnet publish --configuration Release --runtime linux-x64 --self-contained true --output ./binaries Acme.Shop /p:IncludeSourceRevisionInInformationalVersion=false --no-restore")
+	})
+
+	t.Run("FailsWhenNoProjectCanBeFound", func(t *testing.T) {
+		cfg := newTestConfig(t, "library")
+		cfg.Action = "publish"
+		cfg.SolutionName = "Acme.Unknown"
+		runner := &FakeCommandRunner{}
+
+		err := runAction(context.Background(), runner, cfg)
+
+		if err == nil {
+			t.Fatal("expected an error")
+		}
+		assertCommands(t, runner)
+	})
+
+	t.Run("FailsWhenProjectWasRestoredWithoutRuntime", func(t *testing.T) {
+		cfg := newTestConfig(t, "webservice")
+		cfg.Action = "publish"
+		cfg.Solution = nil
+		cfg.WorkingDirectory = writeFiles(t, map[string]string{
+			"src/Acme.Api/obj/project.assets.json": `{"version": 3, "targets": {"net8.0": {}, "net8.0/win-x64": {}}}`,
+		})
+		cfg.Project = "src/Acme.Api"
+		runner := &FakeCommandRunner{}
+
+		err := runAction(context.Background(), runner, cfg)
+
+		if err == nil || !strings.Contains(err.Error(), "was restored for net8.0 without runtime linux-x64") {
+			t.Fatalf("expected an error about the missing runtime, got %v", err)
+		}
+		assertCommands(t, runner)
+	})
+
+	t.Run("PublishesProjectRestoredForRuntime", func(t *testing.T) {
+		cfg := newTestConfig(t, "webservice")
+		cfg.Action = "publish"
+		cfg.Solution = nil
+		cfg.WorkingDirectory = writeFiles(t, map[string]string{
+			"src/Acme.Api/obj/project.assets.json": `{"version": 3, "targets": {"net8.0": {}, "net8.0/win-x64": {}}}`,
+		})
+		cfg.Project = "src/Acme.Api/Acme.Api.csproj"
+		cfg.RuntimeIDs = []string{"win-x64"}
+		cfg.OutputFolder = "./binaries"
+		runner := &FakeCommandRunner{}
+
+		err := runAction(context.Background(), runner, cfg)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertCommands(t, runner, "dotnet publish --configuration Release --runtime win-x64 --self-contained true --output ./binaries src/Acme.Api/Acme.Api.csproj /p:IncludeSourceRevisionInInformationalVersion=false --no-restore")
+	})
+}
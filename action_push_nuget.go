@@ -0,0 +1,509 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	foundation "github.com/estafette/estafette-foundation"
+	"github.com/rs/zerolog/log"
+)
+
+type pushNugetAction struct{}
+
+// the values of the nugetPushPolicy label, which decide what happens when the push to one of several servers fails
+const (
+	pushPolicyFailAll    = "fail-all"
+	pushPolicyBestEffort = "best-effort"
+)
+
+// the values of the nugetExistingVersionPolicy label, which decide what happens with a package of which the server already has the version
+const (
+	existingVersionPolicyFail = "fail"
+	existingVersionPolicySkip = "skip"
+	existingVersionPolicyWarn = "warn"
+)
+
+func (a *pushNugetAction) Name() string {
+	return "push-nuget"
+}
+
+func (a *pushNugetAction) Description() string {
+	return "Pushes the package(s) created by the pack action to a NuGet server."
+}
+
+func (a *pushNugetAction) Labels() []string {
+	return []string{"packagesFolder", "nugetServerUrl", "nugetServerApiKey", "nugetServerName", "nugetPushServerNames", "nugetPushPolicy", "nugetPackageRules", "nugetPackageInclude", "nugetPackageExclude", "nugetSymbolServerName", "nugetExistingVersionPolicy", "nugetSkipDuplicate", "maxRetries", "retryDelay"}
+}
+
+func (a *pushNugetAction) Validate(cfg Config) error {
+	err := validateRetries(cfg)
+	if err != nil {
+		return err
+	}
+
+	switch cfg.NugetPushPolicy {
+	case "", pushPolicyFailAll, pushPolicyBestEffort:
+	default:
+		return fmt.Errorf("the nugetPushPolicy label has to be %v or %v, not %v", pushPolicyFailAll, pushPolicyBestEffort, cfg.NugetPushPolicy)
+	}
+
+	switch cfg.NugetExistingVersionPolicy {
+	case "", existingVersionPolicyFail, existingVersionPolicySkip, existingVersionPolicyWarn:
+	default:
+		return fmt.Errorf("the nugetExistingVersionPolicy label has to be %v, %v or %v, not %v", existingVersionPolicyFail, existingVersionPolicySkip, existingVersionPolicyWarn, cfg.NugetExistingVersionPolicy)
+	}
+
+	for _, rule := range cfg.NugetPackageRules {
+		if rule != "all" && rule != "none" && !foundation.StringArrayContains(packageRules, rule) {
+			return fmt.Errorf("the nugetPackageRules label lists rule %v, which isn't any of %v, all or none", rule, strings.Join(packageRules, ", "))
+		}
+	}
+
+	for _, pattern := range append(append([]string{}, cfg.NugetPackageInclude...), cfg.NugetPackageExclude...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid package id pattern %v: %w", pattern, err)
+		}
+	}
+
+	return nil
+}
+
+func (a *pushNugetAction) Run(ctx context.Context, runner CommandRunner, cfg Config) error {
+
+	// Minimal example with defaults.
+	// image: extensions/dotnet:stable
+	// action: push-nuget
+
+	// Customizations.
+	// image: extensions/dotnet:stable
+	// action: push-nuget
+	// packagesFolder: MyProject/BuildOutput
+	// nugetServerUrl: https://nuget.mycompany.com
+	// nugetServerApikey: 3a4cdeca-3d5b-41a2-ac59-ae4b5c5eaece
+	// nugetSkipDuplicate: true
+	// nugetExistingVersionPolicy: skip
+
+	// Several servers.
+	// image: extensions/dotnet:stable
+	// action: push-nuget
+	// nugetPushServerNames: internal-nuget,github-nuget
+	// nugetPushPolicy: best-effort
+
+	// Symbol packages on a separate symbol server.
+	// image: extensions/dotnet:stable
+	// action: push-nuget
+	// nugetSymbolServerName: symbol-server
+
+	log.Printf("Publishing the nuget package(s)...\n")
+
+	// Determine the NuGet server credentials
+	// If nugetPushServerNames is specified, we push to every server it lists.
+	// If nugetServerURL and nugetServerAPIKey are explicitly specified, we use those.
+	// Otherwise, we use the credential named by nugetServerName, or else push to GitHub.
+	credentials, err := resolvePushNugetServerCredentials(cfg)
+	if err != nil {
+		return err
+	}
+
+	serverURLs := make([]string, 0, len(credentials))
+	for _, credential := range credentials {
+		serverURLs = append(serverURLs, credential.AdditionalProperties.APIURL)
+	}
+	cfg.Report.SetValue("nugetServerUrl", strings.Join(serverURLs, ","))
+
+	// Without a symbol server the symbol packages go to the same server as their packages.
+	symbolCredential, err := resolveSymbolNugetServerCredentials(cfg)
+	if err != nil {
+		return err
+	}
+	if symbolCredential != nil {
+		cfg.Report.SetValue("nugetSymbolServerUrl", symbolCredential.AdditionalProperties.APIURL)
+	}
+
+	packagesBasePath := cfg.PackagesFolder
+	if packagesBasePath == "" {
+		packagesBasePath = filepath.Join(cfg.WorkingDirectory, "src")
+	} else if !filepath.IsAbs(packagesBasePath) {
+		packagesBasePath = filepath.Join(cfg.WorkingDirectory, packagesBasePath)
+	}
+
+	var files, symbolFiles []string
+	err = filepath.Walk(packagesBasePath, func(path string, f os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !f.IsDir() {
+			if isSymbolPackage(path) {
+				symbolFiles = append(symbolFiles, path)
+			} else if filepath.Ext(path) == ".nupkg" {
+				files = append(files, path)
+			}
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return fmt.Errorf("an error occurred while searching for .nupkg files: %w", err)
+	}
+
+	if len(files) == 0 {
+		return fmt.Errorf("no .nupkg files were found under %v", packagesBasePath)
+	}
+
+	// Stale packages of earlier builds can still be in the bin folders, so only the packages of this build are pushed.
+	files = selectPackages(cfg, files)
+	if len(files) == 0 {
+		return fmt.Errorf("none of the .nupkg files under %v were selected to push, the log lists why they were skipped", packagesBasePath)
+	}
+
+	// All packages are checked before anything is pushed, so a broken package never ends up on some of the servers.
+	err = validatePackages(cfg, files)
+	if err != nil {
+		return err
+	}
+
+	// The servers are checked before anything is pushed, so an existing version fails the action without a partial push.
+	filesPerServer, err := checkExistingVersions(ctx, cfg, credentials, files)
+	if err != nil {
+		return err
+	}
+
+	// The symbol packages follow the selection of their packages.
+	symbolPackages := pairSymbolPackages(files, symbolFiles)
+
+	args := []string{
+		"nuget",
+		"push",
+	}
+
+	if cfg.NugetSkipDuplicate {
+		args = append(args, "--skip-duplicate")
+	}
+
+	// Every server gets all packages before the next one, so the results show which servers have the complete set.
+	results := make([]stepResult, 0, len(credentials))
+	pushed := map[string]bool{}
+	pushedSymbols := map[string]bool{}
+	var failures []error
+	for _, credential := range credentials {
+		if len(failures) > 0 && cfg.NugetPushPolicy != pushPolicyBestEffort {
+			results = append(results, stepResult{name: credential.Name, status: "skipped"})
+			continue
+		}
+
+		start := time.Now()
+		filesForServer := filesPerServer[credential.Name]
+		err := pushPackages(ctx, runner, cfg, credential, symbolCredential, args, filesForServer, symbolPackages, pushedSymbols)
+		result := stepResult{name: credential.Name, status: "succeeded", duration: time.Since(start), err: err}
+		if err != nil {
+			result.status = "failed"
+			failures = append(failures, fmt.Errorf("pushing to %v failed: %w", credential.Name, err))
+		} else {
+			for _, f := range filesForServer {
+				pushed[f] = true
+			}
+		}
+		results = append(results, result)
+	}
+
+	if len(credentials) > 1 {
+		log.Info().Msg(formatStepResults("Summary of the pushes per NuGet server:", results))
+	}
+	for _, status := range []string{"succeeded", "failed", "skipped"} {
+		var names []string
+		for _, r := range results {
+			if r.status == status {
+				names = append(names, r.name)
+			}
+		}
+		if len(names) > 0 {
+			cfg.Report.SetValue("nugetServers"+strings.ToUpper(status[:1])+status[1:], strings.Join(names, ","))
+		}
+	}
+
+	for _, f := range files {
+		if pushed[f] {
+			cfg.Report.AddArtifact(relativePath(cfg.WorkingDirectory, f))
+		}
+		if s, ok := symbolPackages[f]; ok && pushedSymbols[s] {
+			cfg.Report.AddArtifact(relativePath(cfg.WorkingDirectory, s))
+		}
+	}
+
+	switch {
+	case len(failures) == 0:
+		return nil
+	case len(credentials) == 1:
+		return results[0].err
+	case cfg.NugetPushPolicy == pushPolicyBestEffort && len(failures) < len(credentials):
+		log.Warn().Err(errors.Join(failures...)).Msgf("Pushed the package(s) to %v of %v NuGet servers.", len(credentials)-len(failures), len(credentials))
+		return nil
+	}
+
+	return errors.Join(failures...)
+}
+
+// Returns the packages to push: the ones created by the pack action if its report is available, or else the ones with the build version,
+// and of those only the ones with an id matching the nugetPackageInclude and not the nugetPackageExclude patterns. The skipped packages are logged with the reason.
+func selectPackages(cfg Config, files []string) []string {
+	packed, packedSource := getPackedPackages(cfg)
+
+	var selected []string
+	for _, f := range files {
+		reason := ""
+		p, readErr := ReadPackage(f)
+		switch {
+		case packed != nil && !packed[strings.ToLower(filepath.Base(f))]:
+			reason = fmt.Sprintf("it wasn't created by %v", packedSource)
+		// a package which can't be read is left to the validation, which reports why
+		case readErr != nil:
+		case packed == nil && cfg.BuildVersion != "" && normalizePackageVersion(p.Nuspec.Metadata.Version) != normalizePackageVersion(cfg.BuildVersion):
+			reason = fmt.Sprintf("its version %v isn't the build version %v", p.Nuspec.Metadata.Version, cfg.BuildVersion)
+		default:
+			reason = matchPackageID(p.Nuspec.Metadata.ID, cfg.NugetPackageInclude, cfg.NugetPackageExclude)
+		}
+
+		if reason != "" {
+			log.Info().Msgf("Skipping package %v, because %v.", relativePath(cfg.WorkingDirectory, f), reason)
+			continue
+		}
+		selected = append(selected, f)
+	}
+
+	return selected
+}
+
+// Returns the lowercase file names of the packages created by the pack action, either earlier in this stage or in an earlier stage which wrote its report, and where they come from.
+// It returns nil if neither is available.
+func getPackedPackages(cfg Config) (map[string]bool, string) {
+	artifacts, source := cfg.Report.getArtifacts(), "the pack step"
+	if !containsPackages(artifacts) {
+		artifacts, source = nil, ""
+		if cfg.ReportPath != "" {
+			reportPath := cfg.ReportPath
+			if !filepath.IsAbs(reportPath) {
+				reportPath = filepath.Join(cfg.WorkingDirectory, reportPath)
+			}
+			if report, err := ReadReport(reportPath); err == nil && report.Action == "pack" && report.Status == "succeeded" {
+				artifacts, source = report.getArtifacts(), "the pack action of report "+cfg.ReportPath
+			}
+		}
+	}
+
+	if !containsPackages(artifacts) {
+		return nil, ""
+	}
+
+	packed := map[string]bool{}
+	for _, a := range artifacts {
+		packed[strings.ToLower(path.Base(filepath.ToSlash(a)))] = true
+	}
+
+	return packed, source
+}
+
+func containsPackages(artifacts []string) bool {
+	for _, a := range artifacts {
+		if strings.EqualFold(filepath.Ext(a), ".nupkg") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Returns why the package id doesn't match the include and exclude patterns, or an empty string if it does.
+func matchPackageID(id string, include, exclude []string) string {
+	matches := func(pattern string) bool {
+		matched, _ := path.Match(strings.ToLower(pattern), strings.ToLower(id))
+		return matched
+	}
+
+	if len(include) > 0 {
+		included := false
+		for _, pattern := range include {
+			included = included || matches(pattern)
+		}
+		if !included {
+			return fmt.Sprintf("its id %v doesn't match any of the nugetPackageInclude patterns %v", id, strings.Join(include, ", "))
+		}
+	}
+
+	for _, pattern := range exclude {
+		if matches(pattern) {
+			return fmt.Sprintf("its id %v matches nugetPackageExclude pattern %v", id, pattern)
+		}
+	}
+
+	return ""
+}
+
+// Returns an error listing the violations per package if any of the packages doesn't pass the rules of nugetPackageRules, after logging the result of every package.
+func validatePackages(cfg Config, files []string) error {
+	if len(cfg.NugetPackageRules) == 0 || foundation.StringArrayContains(cfg.NugetPackageRules, "none") {
+		return nil
+	}
+
+	var sb strings.Builder
+	var invalid []string
+	for _, f := range files {
+		name := relativePath(cfg.WorkingDirectory, f)
+
+		var violations []string
+		p, err := ReadPackage(f)
+		if err != nil {
+			violations = []string{err.Error()}
+		} else {
+			violations = p.validate(cfg.NugetPackageRules, cfg.BuildVersion)
+		}
+
+		if len(violations) == 0 {
+			fmt.Fprintf(&sb, "\n  %v: valid", name)
+			continue
+		}
+		invalid = append(invalid, name)
+		fmt.Fprintf(&sb, "\n  %v: invalid", name)
+		for _, v := range violations {
+			fmt.Fprintf(&sb, "\n    - %v", v)
+		}
+	}
+
+	log.Info().Msgf("Validated the package(s) with rules %v:%v", strings.Join(cfg.NugetPackageRules, ", "), sb.String())
+
+	if len(invalid) > 0 {
+		cfg.Report.SetValue("invalidPackages", strings.Join(invalid, ","))
+		return fmt.Errorf("%v of %v package(s) don't pass the rules of nugetPackageRules, so nothing was pushed:%v", len(invalid), len(files), sb.String())
+	}
+
+	return nil
+}
+
+// Returns the packages to push per server name. With a nugetExistingVersionPolicy, every server is asked up front whether it already has the version of each package;
+// policy fail returns an error listing the existing versions, skip leaves those packages out for that server and warn only logs them.
+func checkExistingVersions(ctx context.Context, cfg Config, credentials []*NugetServerCredentials, files []string) (map[string][]string, error) {
+	filesPerServer := map[string][]string{}
+	// a dry run doesn't query the servers, like it doesn't run any commands
+	if cfg.NugetExistingVersionPolicy == "" || cfg.DryRun {
+		for _, credential := range credentials {
+			filesPerServer[credential.Name] = files
+		}
+		return filesPerServer, nil
+	}
+
+	packages := make([]*Package, 0, len(files))
+	for _, f := range files {
+		p, err := ReadPackage(f)
+		if err != nil {
+			return nil, fmt.Errorf("failed reading package %v to check whether its version exists: %w", f, err)
+		}
+		packages = append(packages, p)
+	}
+
+	var existing []string
+	for _, credential := range credentials {
+		client := NewNugetClient(credential)
+		for i, p := range packages {
+			id, version := p.Nuspec.Metadata.ID, p.Nuspec.Metadata.Version
+			exists, err := client.PackageVersionExists(ctx, id, version)
+			if err != nil {
+				return nil, fmt.Errorf("failed checking whether %v has version %v of package %v: %w", credential.Name, version, id, err)
+			}
+			if !exists {
+				filesPerServer[credential.Name] = append(filesPerServer[credential.Name], files[i])
+				continue
+			}
+
+			existing = append(existing, fmt.Sprintf("%v %v on %v", id, version, credential.Name))
+			switch cfg.NugetExistingVersionPolicy {
+			case existingVersionPolicySkip:
+				log.Info().Msgf("Skipping package %v for %v, because it already has version %v.", id, credential.Name, version)
+			case existingVersionPolicyWarn:
+				log.Warn().Msgf("Server %v already has version %v of package %v, pushing it anyway.", credential.Name, version, id)
+				filesPerServer[credential.Name] = append(filesPerServer[credential.Name], files[i])
+			}
+		}
+	}
+
+	if len(existing) > 0 {
+		cfg.Report.SetValue("existingPackages", strings.Join(existing, ","))
+		if cfg.NugetExistingVersionPolicy == existingVersionPolicyFail {
+			return nil, fmt.Errorf("%v package version(s) already exist, so nothing was pushed:\n  %v", len(existing), strings.Join(existing, "\n  "))
+		}
+	}
+
+	return filesPerServer, nil
+}
+
+// Pushes the packages to the server of the credential, stopping at the first package which fails.
+// A package with a symbol package is pushed without its symbols, after which the symbol package is pushed to the symbol server, or else to the same server.
+// The symbol packages pushed to the symbol server are marked in pushedSymbols, so they're pushed only once when the packages go to several servers.
+func pushPackages(ctx context.Context, runner CommandRunner, cfg Config, credential, symbolCredential *NugetServerCredentials, args []string, files []string, symbolPackages map[string]string, pushedSymbols map[string]bool) error {
+	for _, f := range files {
+		s, hasSymbols := symbolPackages[f]
+
+		argsForPackage := args
+		if hasSymbols {
+			argsForPackage = append(append([]string{}, args...), "--no-symbols")
+		}
+		err := pushPackage(ctx, runner, cfg, credential, argsForPackage, f)
+		if err != nil {
+			return err
+		}
+
+		if !hasSymbols || (symbolCredential != nil && pushedSymbols[s]) {
+			continue
+		}
+		target := credential
+		if symbolCredential != nil {
+			target = symbolCredential
+		}
+		err = pushPackage(ctx, runner, cfg, target, args, s)
+		if err != nil {
+			return err
+		}
+		pushedSymbols[s] = true
+	}
+
+	return nil
+}
+
+// Pushes a single package to the server of the credential, retrying on transient errors.
+func pushPackage(ctx context.Context, runner CommandRunner, cfg Config, credential *NugetServerCredentials, args []string, file string) error {
+	serverURL, apiKey := credential.AdditionalProperties.APIURL, credential.AdditionalProperties.APIKey
+
+	argsForServer := make([]string, 0, len(args)+5)
+	argsForServer = append(argsForServer, args...)
+	argsForServer = append(argsForServer, file, "--source", serverURL, "--api-key", apiKey)
+
+	return runWithRetries(ctx, runner, cfg, fmt.Sprintf("Pushing %v", filepath.Base(file)), Command{Name: "dotnet", Args: argsForServer, Dir: cfg.WorkingDirectory, Secrets: []string{apiKey}})
+}
+
+// Returns whether the file is a symbol package, in either the snupkg or the legacy symbols.nupkg format.
+func isSymbolPackage(file string) bool {
+	name := strings.ToLower(file)
+	return strings.HasSuffix(name, ".snupkg") || strings.HasSuffix(name, ".symbols.nupkg")
+}
+
+// Returns the symbol package next to each of the packages which has one, by the path of the package.
+func pairSymbolPackages(files, symbolFiles []string) map[string]string {
+	symbols := map[string]string{}
+	for _, s := range symbolFiles {
+		symbols[strings.TrimSuffix(strings.TrimSuffix(strings.ToLower(s), ".snupkg"), ".symbols.nupkg")] = s
+	}
+
+	pairs := map[string]string{}
+	for _, f := range files {
+		if s, ok := symbols[strings.TrimSuffix(strings.ToLower(f), ".nupkg")]; ok {
+			pairs[f] = s
+		}
+	}
+
+	return pairs
+}
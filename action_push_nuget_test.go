@@ -0,0 +1,452 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPushNuget(t *testing.T) {
+
+	t.Run("PushesEveryPackageWithMaskedKey", func(t *testing.T) {
+		cfg := newTestConfig(t, "library")
+		cfg.Action = "push-nuget"
+		cfg.WorkingDirectory = t.TempDir()
+		cfg.NugetServerURL = "https://nuget.acme.com/v3/index.json"
+		cfg.NugetServerAPIKey = "explicit-secret-key"
+		cfg.NugetSkipDuplicate = true
+		nupkg := filepath.Join(cfg.WorkingDirectory, "src", "Acme.Lib", "bin", "Release", "Acme.Lib.1.0.0.nupkg")
+		err := os.MkdirAll(filepath.Dir(nupkg), 0755)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = os.WriteFile(nupkg, []byte{}, 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		runner := &FakeCommandRunner{}
+
+		err = runAction(context.Background(), runner, cfg)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertCommands(t, runner, "dotnet nuget push --skip-duplicate "+nupkg+" --source https://nuget.acme.com/v3/index.json --api-key ********")
+	})
+
+	t.Run("FailsWhenNoPackagesAreFound", func(t *testing.T) {
+		cfg := newTestConfig(t, "library")
+		cfg.Action = "push-nuget"
+		cfg.NugetServerCredentialsJSONPath, _ = filepath.Abs("testdata/credentials/nuget_server.json")
+		runner := &FakeCommandRunner{}
+
+		err := runAction(context.Background(), runner, cfg)
+
+		if err == nil {
+			t.Fatal("expected an error")
+		}
+		assertCommands(t, runner)
+	})
+
+	t.Run("PushesToNamedServer", func(t *testing.T) {
+		cfg := newTestConfig(t, "library")
+		cfg.Action = "push-nuget"
+		cfg.WorkingDirectory = t.TempDir()
+		cfg.NugetServerCredentialsJSONPath, _ = filepath.Abs("testdata/credentials/nuget_server.json")
+		cfg.NugetServerName = "internal-nuget"
+		nupkg := writePackage(t, cfg.WorkingDirectory, "Acme.Lib.1.0.0.nupkg")
+		runner := &FakeCommandRunner{}
+
+		err := runAction(context.Background(), runner, cfg)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertCommands(t, runner, "dotnet nuget push "+nupkg+" --source https://nuget.acme.com/v3/index.json --api-key ********")
+	})
+
+	t.Run("PushesToEveryListedServer", func(t *testing.T) {
+		cfg := newTestConfig(t, "library")
+		cfg.Action = "push-nuget"
+		cfg.WorkingDirectory = t.TempDir()
+		cfg.NugetServerCredentialsJSONPath, _ = filepath.Abs("testdata/credentials/nuget_server.json")
+		cfg.NugetPushServerNames = []string{"internal-nuget", "github-nuget"}
+		nupkg := writePackage(t, cfg.WorkingDirectory, "Acme.Lib.1.0.0.nupkg")
+		cfg.Report = NewReport(cfg)
+		runner := &FakeCommandRunner{}
+
+		err := runAction(context.Background(), runner, cfg)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertCommands(t, runner,
+			"dotnet nuget push "+nupkg+" --source https://nuget.acme.com/v3/index.json --api-key ********",
+			"dotnet nuget push "+nupkg+" --source https://nuget.pkg.github.com/acme/index.json --api-key ********")
+		if cfg.Report.Values["nugetServersSucceeded"] != "internal-nuget,github-nuget" {
+			t.Errorf("expected both servers to be reported as succeeded, got %v", cfg.Report.Values)
+		}
+	})
+
+	t.Run("StopsAtFirstFailingServerByDefault", func(t *testing.T) {
+		cfg := newTestConfig(t, "library")
+		cfg.Action = "push-nuget"
+		cfg.WorkingDirectory = t.TempDir()
+		cfg.NugetServerCredentialsJSONPath, _ = filepath.Abs("testdata/credentials/nuget_server.json")
+		cfg.NugetPushServerNames = []string{"internal-nuget", "github-nuget"}
+		writePackage(t, cfg.WorkingDirectory, "Acme.Lib.1.0.0.nupkg")
+		cfg.Report = NewReport(cfg)
+		runner := &FakeCommandRunner{RunFunc: func(command Command) error {
+			if strings.Contains(command.String(), "nuget.acme.com") {
+				return errors.New("exit status 1")
+			}
+			return nil
+		}}
+
+		err := runAction(context.Background(), runner, cfg)
+
+		if err == nil || err.Error() != "pushing to internal-nuget failed: exit status 1" {
+			t.Fatalf("expected the push to internal-nuget to fail, got %v", err)
+		}
+		if len(runner.Commands) != 1 {
+			t.Errorf("expected the push to github-nuget to be skipped, got %q", commandLines(runner))
+		}
+		if cfg.Report.Values["nugetServersSkipped"] != "github-nuget" {
+			t.Errorf("expected github-nuget to be reported as skipped, got %v", cfg.Report.Values)
+		}
+	})
+
+	t.Run("PushesToRemainingServersWithBestEffort", func(t *testing.T) {
+		cfg := newTestConfig(t, "library")
+		cfg.Action = "push-nuget"
+		cfg.WorkingDirectory = t.TempDir()
+		cfg.NugetServerCredentialsJSONPath, _ = filepath.Abs("testdata/credentials/nuget_server.json")
+		cfg.NugetPushServerNames = []string{"internal-nuget", "github-nuget"}
+		cfg.NugetPushPolicy = "best-effort"
+		nupkg := writePackage(t, cfg.WorkingDirectory, "Acme.Lib.1.0.0.nupkg")
+		failing := "nuget.acme.com"
+		cfg.Report = NewReport(cfg)
+		runner := &FakeCommandRunner{RunFunc: func(command Command) error {
+			if strings.Contains(command.String(), failing) {
+				return errors.New("exit status 1")
+			}
+			return nil
+		}}
+
+		err := runAction(context.Background(), runner, cfg)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(runner.Commands) != 2 {
+			t.Errorf("expected a push to both servers, got %q", commandLines(runner))
+		}
+		if len(cfg.Report.Artifacts) != 1 || cfg.Report.Artifacts[0] != relativePath(cfg.WorkingDirectory, nupkg) {
+			t.Errorf("expected the package to be reported as pushed, got %v", cfg.Report.Artifacts)
+		}
+
+		failing = "--source"
+		runner = &FakeCommandRunner{RunFunc: runner.RunFunc}
+		err = runAction(context.Background(), runner, cfg)
+
+		if err == nil || !strings.Contains(err.Error(), "pushing to internal-nuget failed") || !strings.Contains(err.Error(), "pushing to github-nuget failed") {
+			t.Fatalf("expected an error for both servers, got %v", err)
+		}
+	})
+
+	t.Run("ValidatesPackagesBeforePushing", func(t *testing.T) {
+		cfg := newTestConfig(t, "library")
+		cfg.Action = "push-nuget"
+		cfg.WorkingDirectory = t.TempDir()
+		cfg.NugetServerURL = "https://nuget.acme.com/v3/index.json"
+		cfg.NugetServerAPIKey = "explicit-secret-key"
+		cfg.NugetPackageRules = []string{"fileName", "buildVersion", "testAssemblies"}
+		cfg.BuildVersion = "1.2.3"
+		valid := filepath.Join(cfg.WorkingDirectory, "src", "Acme.Lib", "bin", "Release", "Acme.Lib.1.2.3.nupkg")
+		writeNupkg(t, valid, testNuspec, "lib/net8.0/Acme.Lib.dll")
+		writeNupkg(t, filepath.Join(cfg.WorkingDirectory, "src", "Acme.Lib.Tests", "bin", "Release", "Acme.Lib.Tests.1.2.3.nupkg"), testNuspec, "lib/net8.0/Acme.Lib.Tests.dll")
+		runner := &FakeCommandRunner{}
+
+		err := runAction(context.Background(), runner, cfg)
+
+		expected := "1 of 2 package(s) don't pass the rules of nugetPackageRules, so nothing was pushed:\n" +
+			"  src/Acme.Lib/bin/Release/Acme.Lib.1.2.3.nupkg: valid\n" +
+			"  src/Acme.Lib.Tests/bin/Release/Acme.Lib.Tests.1.2.3.nupkg: invalid\n" +
+			"    - the file name doesn't match id Acme.Lib and version 1.2.3, which would make it Acme.Lib.1.2.3.nupkg\n" +
+			"    - it contains test assembly lib/net8.0/Acme.Lib.Tests.dll"
+		if err == nil || err.Error() != expected {
+			t.Fatalf("unexpected error\nexpected:\n%v\nactual:\n%v", expected, err)
+		}
+		assertCommands(t, runner)
+
+		cfg.NugetPackageRules = []string{"none"}
+		err = runAction(context.Background(), runner, cfg)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(runner.Commands) != 2 {
+			t.Errorf("expected both packages to be pushed without rules, got %q", commandLines(runner))
+		}
+	})
+
+	t.Run("SkipsPackagesOfOtherVersions", func(t *testing.T) {
+		cfg := newTestConfig(t, "library")
+		cfg.Action = "push-nuget"
+		cfg.WorkingDirectory = t.TempDir()
+		cfg.NugetServerURL = "https://nuget.acme.com/v3/index.json"
+		cfg.NugetServerAPIKey = "explicit-secret-key"
+		cfg.BuildVersion = "1.2.3"
+		current := filepath.Join(cfg.WorkingDirectory, "src", "Acme.Lib", "bin", "Release", "Acme.Lib.1.2.3.nupkg")
+		writeNupkg(t, current, testNuspec)
+		writeNupkg(t, filepath.Join(cfg.WorkingDirectory, "src", "Acme.Lib", "bin", "Release", "Acme.Lib.1.2.2.nupkg"), strings.Replace(testNuspec, "1.2.3", "1.2.2", 1))
+		runner := &FakeCommandRunner{}
+
+		err := runAction(context.Background(), runner, cfg)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertCommands(t, runner, "dotnet nuget push "+current+" --source https://nuget.acme.com/v3/index.json --api-key ********")
+	})
+
+	t.Run("PushesOnlyPackagesInPackReport", func(t *testing.T) {
+		cfg := newTestConfig(t, "library")
+		cfg.Action = "push-nuget"
+		cfg.WorkingDirectory = t.TempDir()
+		cfg.NugetServerURL = "https://nuget.acme.com/v3/index.json"
+		cfg.NugetServerAPIKey = "explicit-secret-key"
+		cfg.ReportPath = ".estafette/dotnet-report.json"
+		packed := filepath.Join(cfg.WorkingDirectory, "src", "Acme.Lib", "bin", "Release", "Acme.Lib.1.2.3.nupkg")
+		writeNupkg(t, packed, testNuspec)
+		writeNupkg(t, filepath.Join(cfg.WorkingDirectory, "src", "Acme.Lib", "bin", "Debug", "Acme.Lib.1.0.0.nupkg"), strings.Replace(testNuspec, "1.2.3", "1.0.0", 1))
+		packReport := NewReport(Config{Action: "pack"})
+		packReport.AddArtifact("src/Acme.Lib/bin/Release/Acme.Lib.1.2.3.nupkg")
+		packReport.finish(nil)
+		err := packReport.Write(filepath.Join(cfg.WorkingDirectory, cfg.ReportPath))
+		if err != nil {
+			t.Fatal(err)
+		}
+		runner := &FakeCommandRunner{}
+
+		err = runAction(context.Background(), runner, cfg)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertCommands(t, runner, "dotnet nuget push "+packed+" --source https://nuget.acme.com/v3/index.json --api-key ********")
+	})
+
+	t.Run("PushesOnlyPackagesInPackReportForAllSolutions", func(t *testing.T) {
+		cfg := newTestConfig(t, "library")
+		cfg.Action = "push-nuget"
+		cfg.AllSolutions = true
+		cfg.WorkingDirectory = newMonorepo(t, "libs/Acme.Lib/Acme.Lib.sln")
+		cfg.NugetServerURL = "https://nuget.acme.com/v3/index.json"
+		cfg.NugetServerAPIKey = "explicit-secret-key"
+		cfg.ReportPath = ".estafette/dotnet-report.json"
+		packed := filepath.Join(cfg.WorkingDirectory, "libs", "Acme.Lib", "src", "Acme.Lib", "bin", "Release", "Acme.Lib.1.2.3.nupkg")
+		writeNupkg(t, packed, testNuspec)
+		writeNupkg(t, filepath.Join(cfg.WorkingDirectory, "libs", "Acme.Lib", "src", "Acme.Lib", "bin", "Debug", "Acme.Lib.1.0.0.nupkg"), strings.Replace(testNuspec, "1.2.3", "1.0.0", 1))
+		packReport := NewReport(Config{Action: "pack", AllSolutions: true})
+		solutionReport := NewReport(Config{Action: "pack", SolutionName: "Acme.Lib"})
+		solutionReport.AddArtifact("src/Acme.Lib/bin/Release/Acme.Lib.1.2.3.nupkg")
+		solutionReport.finish(nil)
+		packReport.addSolution(solutionReport)
+		packReport.finish(nil)
+		err := packReport.Write(filepath.Join(cfg.WorkingDirectory, cfg.ReportPath))
+		if err != nil {
+			t.Fatal(err)
+		}
+		runner := &FakeCommandRunner{}
+
+		err = runAction(context.Background(), runner, cfg)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertCommands(t, runner, "dotnet nuget push "+packed+" --source https://nuget.acme.com/v3/index.json --api-key ********")
+	})
+
+	t.Run("PushesOnlyPackagesOfPackStep", func(t *testing.T) {
+		cfg := newTestConfig(t, "library")
+		cfg.Action = "push-nuget"
+		cfg.WorkingDirectory = t.TempDir()
+		cfg.NugetServerURL = "https://nuget.acme.com/v3/index.json"
+		cfg.NugetServerAPIKey = "explicit-secret-key"
+		packed := writePackage(t, cfg.WorkingDirectory, "Acme.Lib.1.2.3.nupkg")
+		writePackage(t, cfg.WorkingDirectory, "Acme.Lib.1.2.2.nupkg")
+		cfg.Report = NewReport(cfg)
+		cfg.Report.AddArtifact(relativePath(cfg.WorkingDirectory, packed))
+		runner := &FakeCommandRunner{}
+
+		err := runAction(context.Background(), runner, cfg)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertCommands(t, runner, "dotnet nuget push "+packed+" --source https://nuget.acme.com/v3/index.json --api-key ********")
+	})
+
+	t.Run("SelectsPackagesByIdPatterns", func(t *testing.T) {
+		cfg := newTestConfig(t, "library")
+		cfg.Action = "push-nuget"
+		cfg.WorkingDirectory = t.TempDir()
+		cfg.NugetServerURL = "https://nuget.acme.com/v3/index.json"
+		cfg.NugetServerAPIKey = "explicit-secret-key"
+		cfg.NugetPackageInclude = []string{"acme.*"}
+		cfg.NugetPackageExclude = []string{"*.Internal"}
+		for _, id := range []string{"Acme.Lib", "Acme.Lib.Internal", "Contoso.Lib"} {
+			writeNupkg(t, filepath.Join(cfg.WorkingDirectory, "src", id, "bin", "Release", id+".1.2.3.nupkg"), strings.Replace(testNuspec, "<id>Acme.Lib</id>", "<id>"+id+"</id>", 1))
+		}
+		runner := &FakeCommandRunner{}
+
+		err := runAction(context.Background(), runner, cfg)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertCommands(t, runner, "dotnet nuget push "+filepath.Join(cfg.WorkingDirectory, "src", "Acme.Lib", "bin", "Release", "Acme.Lib.1.2.3.nupkg")+" --source https://nuget.acme.com/v3/index.json --api-key ********")
+
+		cfg.NugetPackageInclude = []string{"Fabrikam.*"}
+		err = runAction(context.Background(), runner, cfg)
+
+		if err == nil || !strings.Contains(err.Error(), "none of the .nupkg files") {
+			t.Fatalf("expected an error about no selected packages, got %v", err)
+		}
+	})
+
+	t.Run("PushesSymbolPackagesToSameServer", func(t *testing.T) {
+		cfg := newTestConfig(t, "library")
+		cfg.Action = "push-nuget"
+		cfg.WorkingDirectory = t.TempDir()
+		cfg.NugetServerURL = "https://nuget.acme.com/v3/index.json"
+		cfg.NugetServerAPIKey = "explicit-secret-key"
+		nupkg := writePackage(t, cfg.WorkingDirectory, "Acme.Lib.1.0.0.nupkg")
+		snupkg := writePackage(t, cfg.WorkingDirectory, "Acme.Lib.1.0.0.snupkg")
+		runner := &FakeCommandRunner{}
+
+		err := runAction(context.Background(), runner, cfg)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertCommands(t, runner,
+			"dotnet nuget push --no-symbols "+nupkg+" --source https://nuget.acme.com/v3/index.json --api-key ********",
+			"dotnet nuget push "+snupkg+" --source https://nuget.acme.com/v3/index.json --api-key ********")
+	})
+
+	t.Run("PushesSymbolPackagesOnceToSymbolServer", func(t *testing.T) {
+		cfg := newTestConfig(t, "library")
+		cfg.Action = "push-nuget"
+		cfg.WorkingDirectory = t.TempDir()
+		cfg.NugetServerCredentialsJSONPath, _ = filepath.Abs("testdata/credentials/nuget_server.json")
+		cfg.NugetPushServerNames = []string{"github-nuget", "internal-nuget"}
+		cfg.NugetSymbolServerName = "internal-nuget"
+		nupkg := writePackage(t, cfg.WorkingDirectory, "Acme.Lib.1.0.0.nupkg")
+		symbols := writePackage(t, cfg.WorkingDirectory, "Acme.Lib.1.0.0.symbols.nupkg")
+		cfg.Report = NewReport(cfg)
+		runner := &FakeCommandRunner{}
+
+		err := runAction(context.Background(), runner, cfg)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertCommands(t, runner,
+			"dotnet nuget push --no-symbols "+nupkg+" --source https://nuget.pkg.github.com/acme/index.json --api-key ********",
+			"dotnet nuget push "+symbols+" --source https://nuget.acme.com/v3/index.json --api-key ********",
+			"dotnet nuget push --no-symbols "+nupkg+" --source https://nuget.acme.com/v3/index.json --api-key ********")
+		if cfg.Report.Values["nugetSymbolServerUrl"] != "https://nuget.acme.com/v3/index.json" {
+			t.Errorf("unexpected symbol server url in the report: %v", cfg.Report.Values["nugetSymbolServerUrl"])
+		}
+		expectedArtifacts := []string{"src/Acme.Lib/bin/Release/Acme.Lib.1.0.0.nupkg", "src/Acme.Lib/bin/Release/Acme.Lib.1.0.0.symbols.nupkg"}
+		if !reflect.DeepEqual(cfg.Report.Artifacts, expectedArtifacts) {
+			t.Errorf("expected artifacts %v, got %v", expectedArtifacts, cfg.Report.Artifacts)
+		}
+	})
+
+	t.Run("ChecksExistingVersionsBeforePushing", func(t *testing.T) {
+		server := newTestFeed(t, map[string]string{
+			"/v3/index.json":                        `{"version": "3.0.0", "resources": [{"@id": "{url}/v3/flatcontainer/", "@type": "PackageBaseAddress/3.0.0"}]}`,
+			"/v3/flatcontainer/acme.lib/index.json": `{"versions": ["1.2.3"]}`,
+		})
+		newConfig := func(policy string) (Config, string, string) {
+			cfg := newTestConfig(t, "library")
+			cfg.Action = "push-nuget"
+			cfg.WorkingDirectory = t.TempDir()
+			cfg.NugetServerURL = server.URL + "/v3/index.json"
+			cfg.NugetServerAPIKey = "explicit-secret-key"
+			cfg.NugetExistingVersionPolicy = policy
+			existing := filepath.Join(cfg.WorkingDirectory, "src", "Acme.Lib", "bin", "Release", "Acme.Lib.1.2.3.nupkg")
+			writeNupkg(t, existing, testNuspec)
+			added := filepath.Join(cfg.WorkingDirectory, "src", "Acme.Other", "bin", "Release", "Acme.Other.1.2.3.nupkg")
+			writeNupkg(t, added, strings.Replace(testNuspec, "<id>Acme.Lib</id>", "<id>Acme.Other</id>", 1))
+			cfg.Report = NewReport(cfg)
+			return cfg, existing, added
+		}
+
+		cfg, _, _ := newConfig("fail")
+		runner := &FakeCommandRunner{}
+		err := runAction(context.Background(), runner, cfg)
+		if err == nil || !strings.Contains(err.Error(), "Acme.Lib 1.2.3 on nuget-server") {
+			t.Fatalf("expected an error about the existing version, got %v", err)
+		}
+		assertCommands(t, runner)
+
+		cfg, _, added := newConfig("skip")
+		runner = &FakeCommandRunner{}
+		err = runAction(context.Background(), runner, cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertCommands(t, runner, "dotnet nuget push "+added+" --source "+cfg.NugetServerURL+" --api-key ********")
+		if cfg.Report.Values["existingPackages"] != "Acme.Lib 1.2.3 on nuget-server" {
+			t.Errorf("unexpected existing packages in the report: %v", cfg.Report.Values["existingPackages"])
+		}
+
+		cfg, existing, added := newConfig("warn")
+		runner = &FakeCommandRunner{}
+		err = runAction(context.Background(), runner, cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertCommands(t, runner,
+			"dotnet nuget push "+existing+" --source "+cfg.NugetServerURL+" --api-key ********",
+			"dotnet nuget push "+added+" --source "+cfg.NugetServerURL+" --api-key ********")
+	})
+
+	t.Run("FailsForUnknownPolicy", func(t *testing.T) {
+		cfg := newTestConfig(t, "library")
+		cfg.Action = "push-nuget"
+		cfg.NugetPushPolicy = "yolo"
+		runner := &FakeCommandRunner{}
+
+		err := runAction(context.Background(), runner, cfg)
+
+		if err == nil || !strings.Contains(err.Error(), "nugetPushPolicy") {
+			t.Fatalf("expected an error about the policy, got %v", err)
+		}
+	})
+}
+
+// Writes an empty package to the bin folder of a project under src, where push-nuget looks for them by default, and returns its path.
+func writePackage(t *testing.T, workingDir, name string) string {
+	t.Helper()
+
+	nupkg := filepath.Join(workingDir, "src", "Acme.Lib", "bin", "Release", name)
+	err := os.MkdirAll(filepath.Dir(nupkg), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(nupkg, []byte{}, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return nupkg
+}
@@ -0,0 +1,375 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	foundation "github.com/estafette/estafette-foundation"
+	"github.com/rs/zerolog/log"
+)
+
+type restoreAction struct{}
+
+func (a *restoreAction) Name() string {
+	return "restore"
+}
+
+func (a *restoreAction) Description() string {
+	return "Restores the package dependencies with dotnet restore."
+}
+
+func (a *restoreAction) Labels() []string {
+	return []string{"nugetSources", "nugetServerUrl", "nugetServerApiKey", "nugetServerName", "nugetServerNames", "nugetServerUsername", "nugetSourceName", "packageSourceMapping", "lockedMode", "packageCacheDirectory", "runtimeId", "offline", "offlineSource", "maxRetries", "retryDelay"}
+}
+
+func (a *restoreAction) Validate(cfg Config) error {
+	err := validateRetries(cfg)
+	if err != nil {
+		return err
+	}
+
+	_, err = parseMapping(cfg.PackageSourceMapping)
+	if err != nil {
+		return fmt.Errorf("invalid packageSourceMapping label: %w", err)
+	}
+
+	if cfg.Offline {
+		if cfg.OfflineSource == "" && cfg.PackageCacheDirectory == "" {
+			return fmt.Errorf("restoring offline needs the offlineSource or packageCacheDirectory label, to have the packages locally")
+		}
+		if cfg.NugetSources != "" || cfg.PackageSourceMapping != "" {
+			return fmt.Errorf("restoring offline only uses the local packages, so it can't be combined with the nugetSources or packageSourceMapping label")
+		}
+	}
+
+	return nil
+}
+
+func (a *restoreAction) Run(ctx context.Context, runner CommandRunner, cfg Config) error {
+
+	// Minimal example with defaults.
+	// image: extensions/dotnet:stable
+	// action: restore
+
+	// Determine the NuGet server credentials for restoring
+	// 1. If nugetServerURL and nugetServerAPIKey are explicitly specified, we add a source using those.
+	// 2. If we have the default credentials from the server level, and nugetServerNames is specified, we add a source for every credential it lists, or for all of them if it's set to all.
+	// 3. If we have the default credentials from the server level, and nugetServerName is explicitly specified, we look for the credential with the specified name.
+	// 4. If we have the default credentials from the server level, and nugetServerName is not specified, we take the first credential. (This is the sensible default if we're using only one NuGet server.)
+	// The sources are added to a temporary copy of the NuGet.config file of the repository or else of the defaults, which is then used for the restore,
+	// so neither the file in the repository nor the user-level NuGet.Config is ever changed, and solutions restored in parallel don't share a config.
+	// The keys are passed to the restore in environment variables, so they never end up on disk or in the process arguments.
+
+	// Projects which restore with a lock file but don't have one yet would get a new one in locked mode, instead of failing on changed dependencies.
+	var lockedProjects []lockedProject
+	if cfg.Solution != nil {
+		var err error
+		lockedProjects, err = getLockedProjects(cfg.Solution, cfg.WorkingDirectory)
+		if err != nil {
+			return err
+		}
+
+		var missing []string
+		for _, p := range lockedProjects {
+			if !foundation.FileExists(p.lockFilePath) {
+				missing = append(missing, p.path)
+			}
+		}
+		if len(missing) > 0 {
+			if cfg.LockedMode {
+				return fmt.Errorf("the project(s) %v enable RestorePackagesWithLockFile but don't have a lock file, restore without lockedMode and commit the generated packages.lock.json files", strings.Join(missing, ", "))
+			}
+			log.Warn().Msgf("The project(s) %v enable RestorePackagesWithLockFile but don't have a lock file.", strings.Join(missing, ", "))
+		}
+	}
+
+	// Offline the packages can only come from a local folder feed, or else from the packages folder itself after extracting the package cache.
+	offlineFolder := ""
+	var credentials []*NugetServerCredentials
+	if cfg.Offline {
+		offlineFolder = filepath.Join(cfg.WorkingDirectory, packagesDirectory)
+		if cfg.OfflineSource != "" {
+			offlineFolder = cfg.OfflineSource
+			if !filepath.IsAbs(offlineFolder) {
+				offlineFolder = filepath.Join(cfg.WorkingDirectory, offlineFolder)
+			}
+		}
+		log.Info().Msgf("Restoring offline from %v, without any remote sources.", offlineFolder)
+	} else {
+		var err error
+		credentials, err = resolveRestoreNugetServerCredentials(cfg)
+		if err != nil {
+			return err
+		}
+		if len(credentials) == 0 {
+			log.Printf("No custom NuGet credentials were found.\n")
+		}
+	}
+
+	nugetConfig, err := prepareRestoreNugetConfig(ctx, runner, cfg, credentials, offlineFolder)
+	if err != nil {
+		return err
+	}
+	defer nugetConfig.cleanup()
+
+	var serverURLs []string
+	for _, s := range nugetConfig.sources {
+		serverURLs = append(serverURLs, s.credential.AdditionalProperties.APIURL)
+	}
+	if len(serverURLs) > 0 {
+		cfg.Report.SetValue("nugetServerUrls", strings.Join(serverURLs, ","))
+	}
+
+	log.Printf("Restoring packages...\n")
+	args := appendSolutionArg([]string{"restore"}, cfg)
+	args = append(args,
+		"--packages",
+		packagesDirectory, // This is needed so the packages are restored into the working directory, so they're not lost between the stages.
+	)
+
+	if cfg.NugetSources != "" {
+		nugetSourcesArray := strings.Split(cfg.NugetSources, ",")
+
+		for _, source := range nugetSourcesArray {
+			args = append(args, "--source", source)
+		}
+	}
+
+	if nugetConfig.path != "" {
+		args = append(args, "--configfile", nugetConfig.path)
+	}
+
+	// the runtime specific assets are needed by publish, which runs without restoring
+	for _, runtimeID := range cfg.RuntimeIDs {
+		args = append(args, "--runtime", runtimeID)
+	}
+
+	if cfg.LockedMode {
+		args = append(args, "--locked-mode")
+	}
+
+	cacheArchivePath := ""
+	if cfg.PackageCacheDirectory != "" {
+		cacheArchivePath = loadPackageCache(cfg)
+	}
+
+	var output bytes.Buffer
+	command := Command{Name: "dotnet", Args: args, Dir: cfg.WorkingDirectory}
+	command.Env, command.Secrets = getNugetCredentialsEnv(nugetConfig.sources)
+	if cfg.LockedMode {
+		command.Output = &output
+	}
+
+	err = runWithRetries(ctx, runner, cfg, "Restoring the packages", command)
+	if cfg.Offline && !cfg.DryRun {
+		// the restore fails on packages it can't find, but packages resolved from elsewhere, like a fallback folder, are only found this way
+		missingErr := checkPackagesAvailableOffline(cfg, offlineFolder, err)
+		if missingErr != nil {
+			return missingErr
+		}
+	}
+	if err != nil {
+		if cfg.LockedMode {
+			return describeLockFileDivergences(lockedProjects, output.String(), err)
+		}
+		return err
+	}
+
+	if cacheArchivePath != "" {
+		savePackageCache(cfg, cacheArchivePath)
+	}
+
+	return nil
+}
+
+// restoreNugetConfig is the NuGet config a restore uses, with the sources of the credentials registered in it
+type restoreNugetConfig struct {
+	// path is the temporary config to restore with, or empty if the NuGet config of the repository or the defaults are used as they are
+	path string
+	// sources are the sources the credentials authenticate, their keys are passed to the restore in its environment
+	sources []nugetSourceCredential
+	// cleanup removes the temporary config again
+	cleanup func()
+}
+
+// Writes the sources of the credentials and the package source mapping to a temporary copy of the NuGet config of the repository, or else of the defaults, and registers the sources in it through the runner.
+// Offline the temporary config only has the offline folder as source.
+func prepareRestoreNugetConfig(ctx context.Context, runner CommandRunner, cfg Config, credentials []*NugetServerCredentials, offlineFolder string) (*restoreNugetConfig, error) {
+	mapping, err := parseMapping(cfg.PackageSourceMapping)
+	if err != nil {
+		return nil, fmt.Errorf("invalid packageSourceMapping label: %w", err)
+	}
+
+	var nugetConfig *NugetConfig
+	var configContent []byte
+	configName := "the generated NuGet config"
+	nugetConfigPath := findNugetConfigPath(cfg.WorkingDirectory)
+	if cfg.Offline {
+		configName = "the offline NuGet config"
+		configContent = []byte(renderOfflineNugetConfig("offline", offlineFolder))
+	} else if nugetConfigPath != "" {
+		configName = relativePath(cfg.WorkingDirectory, nugetConfigPath)
+		configContent, err = os.ReadFile(nugetConfigPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed reading %v: %w", configName, err)
+		}
+		// the copy is in another directory, so its relative paths have to be resolved against the one of the original
+		configContent = makeNugetConfigPathsAbsolute(configContent, filepath.Dir(nugetConfigPath))
+	} else if len(credentials) > 0 || len(mapping) > 0 {
+		configContent = []byte(defaultNugetConfig)
+	}
+
+	config := &restoreNugetConfig{cleanup: func() {}}
+	if configContent != nil {
+		nugetConfig, err = parseNugetConfig(configContent, configName)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(mapping) > 0 {
+			if nugetConfig.HasPackageSourceMapping {
+				return nil, fmt.Errorf("%v already has a packageSourceMapping section, so it can't be set with the packageSourceMapping label as well", configName)
+			}
+			configContent, err = addNugetConfigSection(configContent, renderPackageSourceMapping(mapping))
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		if len(credentials) > 0 || len(mapping) > 0 || cfg.Offline {
+			config.path, config.cleanup, err = writeNugetConfigToTempDir(configContent)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// The sources are registered without credentials, those are passed to the restore in its environment instead.
+	config.sources = getNugetSourceCredentials(credentials, nugetConfig)
+	var commands []Command
+	var added []NugetSource
+	var authenticatedSources []string
+	for _, s := range config.sources {
+		serverURL := s.credential.AdditionalProperties.APIURL
+		authenticatedSources = append(authenticatedSources, s.sourceName)
+
+		var args []string
+		switch {
+		case s.existing == nil:
+			args = []string{"nuget", "add", "source", "--name", s.sourceName, serverURL}
+			added = append(added, NugetSource{Name: s.sourceName, URL: serverURL})
+		case strings.TrimSuffix(s.existing.URL, "/") != strings.TrimSuffix(serverURL, "/"):
+			log.Printf("Changing the url of NuGet source %v of %v to the one of its credentials.\n", s.sourceName, configName)
+			args = []string{"nuget", "update", "source", s.sourceName, "--source", serverURL}
+			s.existing.URL = serverURL
+		default:
+			log.Printf("Authenticating NuGet source %v of %v.\n", s.sourceName, configName)
+			continue
+		}
+		args = append(args, "--configfile", config.path)
+
+		commands = append(commands, Command{Name: "dotnet", Args: args, Dir: cfg.WorkingDirectory})
+	}
+
+	if nugetConfig != nil {
+		nugetConfig.Sources = append(nugetConfig.Sources, added...)
+		log.Info().Msg(formatNugetSources(fmt.Sprintf("Effective NuGet sources of %v:", configName), nugetConfig.Sources, authenticatedSources))
+
+		// a mapping to a source which doesn't exist would make every package it matches fail to restore
+		err := validatePackageSourceMapping(mapping, nugetConfig.Sources)
+		if err != nil {
+			config.cleanup()
+			return nil, err
+		}
+	}
+
+	for _, command := range commands {
+		err := runner.Run(ctx, command)
+		if err != nil {
+			config.cleanup()
+			return nil, err
+		}
+	}
+
+	return config, nil
+}
+
+// Returns an error listing the packages, with their versions, which the projects need but which aren't available locally, so they can be mirrored to the offline folder.
+func checkPackagesAvailableOffline(cfg Config, offlineFolder string, restoreErr error) error {
+	assetsFilePaths, err := getProjectAssetsFilePaths(cfg.Solution, cfg.WorkingDirectory)
+	if err != nil {
+		return err
+	}
+
+	var missing []string
+	for _, assetsFilePath := range assetsFilePaths {
+		assetsFile, err := ReadProjectAssetsFile(assetsFilePath)
+		if err != nil {
+			return err
+		}
+		missing = append(missing, assetsFile.getMissingPackages()...)
+	}
+	missing = dedupe(missing)
+	sort.Strings(missing)
+
+	if len(missing) == 0 {
+		return nil
+	}
+	cfg.Report.SetValue("missingPackages", strings.Join(missing, ","))
+
+	var sb strings.Builder
+	for _, p := range missing {
+		fmt.Fprintf(&sb, "\n  %v", p)
+	}
+	if restoreErr != nil {
+		return fmt.Errorf("restoring offline failed, because %v package(s) aren't available locally; mirror them to %v:%v\n%w", len(missing), offlineFolder, sb.String(), restoreErr)
+	}
+
+	return fmt.Errorf("restoring offline failed, because %v package(s) weren't restored from the local packages; mirror them to %v:%v", len(missing), offlineFolder, sb.String())
+}
+
+// Returns an error if the mapping refers to a source that isn't in the list of sources.
+func validatePackageSourceMapping(mapping map[string][]string, sources []NugetSource) error {
+	names := make([]string, 0, len(sources))
+	for _, s := range sources {
+		names = append(names, s.Name)
+	}
+
+	var unknown []string
+	for name := range mapping {
+		found := false
+		for _, s := range sources {
+			found = found || strings.EqualFold(s.Name, name)
+		}
+		if !found {
+			unknown = append(unknown, name)
+		}
+	}
+	sort.Strings(unknown)
+
+	if len(unknown) > 0 {
+		return fmt.Errorf("the packageSourceMapping label maps packages to source(s) %v, which aren't any of the sources %v", strings.Join(unknown, ", "), strings.Join(names, ", "))
+	}
+
+	return nil
+}
+
+// Returns the list of sources, marking the ones with the injected credentials.
+func formatNugetSources(title string, sources []NugetSource, authenticatedSources []string) string {
+	var sb strings.Builder
+
+	sb.WriteString(title)
+	for _, s := range sources {
+		fmt.Fprintf(&sb, "\n  %v: %v", s.Name, s.URL)
+		if foundation.StringArrayContains(authenticatedSources, s.Name) {
+			sb.WriteString(" (authenticated)")
+		}
+	}
+
+	return sb.String()
+}
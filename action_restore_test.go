@@ -0,0 +1,597 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRestore(t *testing.T) {
+
+	t.Run("RestoresIntoTheWorkingDirectory", func(t *testing.T) {
+		cfg := newTestConfig(t, "webservice")
+		cfg.Action = "restore"
+		runner := &FakeCommandRunner{}
+
+		err := runAction(context.Background(), runner, cfg)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertCommands(t, runner, "dotnet restore Acme.FooApi.sln --packages .nuget/packages")
+		if runner.Commands[0].Dir != cfg.WorkingDirectory {
+			t.Errorf("expected command to run in %v, got %v", cfg.WorkingDirectory, runner.Commands[0].Dir)
+		}
+	})
+
+	t.Run("RestoresForRuntimes", func(t *testing.T) {
+		cfg := newTestConfig(t, "webservice")
+		cfg.Action = "restore"
+		cfg.RuntimeIDs = []string{"linux-x64", "linux-musl-x64"}
+		runner := &FakeCommandRunner{}
+
+		err := runAction(context.Background(), runner, cfg)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertCommands(t, runner, "dotnet restore Acme.FooApi.sln --packages .nuget/packages --runtime linux-x64 --runtime linux-musl-x64")
+	})
+
+	t.Run("AddsSourceFromCredentialsFileWithMaskedKey", func(t *testing.T) {
+		cfg := newTestConfig(t, "webservice")
+		cfg.Action = "restore"
+		cfg.NugetServerCredentialsJSONPath, _ = filepath.Abs("testdata/credentials/nuget_server.json")
+		cfg.NugetSources = "https://api.nuget.org/v3/index.json,https://nuget.acme.com/v3/index.json"
+		runner := &FakeCommandRunner{}
+
+		err := runAction(context.Background(), runner, cfg)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+		configPath := runner.Commands[0].Args[len(runner.Commands[0].Args)-1]
+		assertCommands(t, runner,
+			"dotnet nuget add source --name github-nuget https://nuget.pkg.github.com/acme/index.json --configfile "+configPath,
+			"NuGetPackageSourceCredentials_github-nuget=******** VSS_NUGET_EXTERNAL_FEED_ENDPOINTS=******** dotnet restore Acme.FooApi.sln --packages .nuget/packages --source https://api.nuget.org/v3/index.json --source https://nuget.acme.com/v3/index.json --configfile "+configPath)
+		expectedEnv := []string{
+			"NuGetPackageSourceCredentials_github-nuget=Username=acme-bot;Password=github-secret-key",
+			`VSS_NUGET_EXTERNAL_FEED_ENDPOINTS={"endpointCredentials":[{"endpoint":"https://nuget.pkg.github.com/acme/index.json","username":"acme-bot","password":"github-secret-key"}]}`,
+		}
+		if strings.Join(runner.Commands[1].Env, "\n") != strings.Join(expectedEnv, "\n") {
+			t.Errorf("expected the actual key to be passed to dotnet in its environment, got %v", runner.Commands[1].Env)
+		}
+		for _, command := range runner.Commands {
+			if strings.Contains(strings.Join(command.Args, " "), "github-secret-key") {
+				t.Errorf("expected the key not to be passed as an argument, got %v", command.Args)
+			}
+		}
+	})
+
+	t.Run("OverridesUsernameAndSourceNameWithLabels", func(t *testing.T) {
+		cfg := newTestConfig(t, "webservice")
+		cfg.Action = "restore"
+		cfg.NugetServerCredentialsJSONPath, _ = filepath.Abs("testdata/credentials/nuget_server.json")
+		cfg.NugetServerUsername = "octocat"
+		cfg.NugetSourceName = "github"
+		runner := &FakeCommandRunner{}
+
+		err := runAction(context.Background(), runner, cfg)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+		configPath := runner.Commands[0].Args[len(runner.Commands[0].Args)-1]
+		assertCommands(t, runner,
+			"dotnet nuget add source --name github https://nuget.pkg.github.com/acme/index.json --configfile "+configPath,
+			"NuGetPackageSourceCredentials_github=******** VSS_NUGET_EXTERNAL_FEED_ENDPOINTS=******** dotnet restore Acme.FooApi.sln --packages .nuget/packages --configfile "+configPath)
+	})
+
+	t.Run("NamesSourceFromLabelsAfterTheServer", func(t *testing.T) {
+		cfg := newTestConfig(t, "webservice")
+		cfg.Action = "restore"
+		cfg.NugetServerURL = "https://nuget.acme.com/v3/index.json"
+		cfg.NugetServerAPIKey = "label-secret-key"
+		runner := &FakeCommandRunner{}
+
+		err := runAction(context.Background(), runner, cfg)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+		configPath := runner.Commands[0].Args[len(runner.Commands[0].Args)-1]
+		assertCommands(t, runner,
+			"dotnet nuget add source --name nuget-server https://nuget.acme.com/v3/index.json --configfile "+configPath,
+			"NuGetPackageSourceCredentials_nuget-server=******** VSS_NUGET_EXTERNAL_FEED_ENDPOINTS=******** dotnet restore Acme.FooApi.sln --packages .nuget/packages --configfile "+configPath)
+	})
+
+	t.Run("AddsSourceForEverySelectedCredential", func(t *testing.T) {
+		cfg := newTestConfig(t, "webservice")
+		cfg.Action = "restore"
+		cfg.NugetServerCredentialsJSONPath, _ = filepath.Abs("testdata/credentials/nuget_server.json")
+		cfg.NugetServerNames = []string{"github-nuget", "internal-nuget"}
+		runner := &FakeCommandRunner{}
+
+		err := runAction(context.Background(), runner, cfg)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+		configPath := runner.Commands[0].Args[len(runner.Commands[0].Args)-1]
+		assertCommands(t, runner,
+			"dotnet nuget add source --name github-nuget https://nuget.pkg.github.com/acme/index.json --configfile "+configPath,
+			"dotnet nuget add source --name internal-nuget https://nuget.acme.com/v3/index.json --configfile "+configPath,
+			"NuGetPackageSourceCredentials_github-nuget=******** NuGetPackageSourceCredentials_internal-nuget=******** VSS_NUGET_EXTERNAL_FEED_ENDPOINTS=******** dotnet restore Acme.FooApi.sln --packages .nuget/packages --configfile "+configPath)
+	})
+
+	t.Run("AddsSourceForAllCredentialsWithUniqueNames", func(t *testing.T) {
+		cfg := newTestConfig(t, "webservice")
+		cfg.Action = "restore"
+		cfg.NugetServerCredentialsJSONPath, _ = filepath.Abs("testdata/credentials/nuget_server.json")
+		cfg.NugetServerNames = []string{"all"}
+		cfg.NugetServerURL = "https://nuget.example.com/v3/index.json"
+		cfg.NugetServerAPIKey = "label-secret-key"
+		cfg.NugetSourceName = "internal-nuget"
+		runner := &FakeCommandRunner{}
+
+		err := runAction(context.Background(), runner, cfg)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+		configPath := runner.Commands[0].Args[len(runner.Commands[0].Args)-1]
+		assertCommands(t, runner,
+			"dotnet nuget add source --name internal-nuget https://nuget.example.com/v3/index.json --configfile "+configPath,
+			"dotnet nuget add source --name internal-nuget-2 https://nuget.acme.com/v3/index.json --configfile "+configPath,
+			"dotnet nuget add source --name github-nuget https://nuget.pkg.github.com/acme/index.json --configfile "+configPath,
+			"NuGetPackageSourceCredentials_internal-nuget=******** NuGetPackageSourceCredentials_internal-nuget-2=******** NuGetPackageSourceCredentials_github-nuget=******** VSS_NUGET_EXTERNAL_FEED_ENDPOINTS=******** dotnet restore Acme.FooApi.sln --packages .nuget/packages --configfile "+configPath)
+	})
+
+	t.Run("RegistersSourcesInSeparateConfigPerSolution", func(t *testing.T) {
+		cfg := newTestConfig(t, "webservice")
+		cfg.Action = "restore"
+		cfg.AllSolutions = true
+		cfg.Parallelism = 2
+		cfg.WorkingDirectory = newMonorepo(t, "services/Acme.Orders/Acme.Orders.sln", "libs/Acme.Common/Acme.Common.sln")
+		cfg.NugetServerCredentialsJSONPath, _ = filepath.Abs("testdata/credentials/nuget_server.json")
+		runner := &FakeCommandRunner{}
+
+		err := runAction(context.Background(), runner, cfg)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(runner.Commands) != 4 {
+			t.Fatalf("expected 4 commands, got %q", commandLines(runner))
+		}
+		configPaths := map[string]string{}
+		for _, c := range runner.Commands {
+			if len(c.Args) < 2 || c.Args[len(c.Args)-2] != "--configfile" {
+				t.Fatalf("expected every command to use a temporary nuget config, got %v", c.String())
+			}
+			configPath := c.Args[len(c.Args)-1]
+			if previous, ok := configPaths[c.Dir]; ok && previous != configPath {
+				t.Errorf("expected the commands of solution %v to share a nuget config, got %v and %v", c.Dir, previous, configPath)
+			}
+			configPaths[c.Dir] = configPath
+		}
+		if len(configPaths) != 2 || configPaths[filepath.Join(cfg.WorkingDirectory, "services", "Acme.Orders")] == configPaths[filepath.Join(cfg.WorkingDirectory, "libs", "Acme.Common")] {
+			t.Errorf("expected a separate nuget config per solution, got %v", configPaths)
+		}
+	})
+
+	t.Run("FailsForUnknownServerName", func(t *testing.T) {
+		cfg := newTestConfig(t, "webservice")
+		cfg.Action = "restore"
+		cfg.NugetServerCredentialsJSONPath, _ = filepath.Abs("testdata/credentials/nuget_server.json")
+		cfg.NugetServerNames = []string{"github-nuget", "unknown-nuget"}
+		runner := &FakeCommandRunner{}
+
+		err := runAction(context.Background(), runner, cfg)
+
+		if err == nil || !strings.Contains(err.Error(), "unknown-nuget") {
+			t.Fatalf("expected an error about the unknown credential, got %v", err)
+		}
+		assertCommands(t, runner)
+	})
+
+	t.Run("AddsSourceToTemporaryCopyOfCommittedNugetConfig", func(t *testing.T) {
+		cfg := newTestConfig(t, "webservice")
+		cfg.Action = "restore"
+		cfg.Solution = nil
+		cfg.WorkingDirectory = t.TempDir()
+		cfg.NugetServerCredentialsJSONPath, _ = filepath.Abs("testdata/credentials/nuget_server.json")
+		repositoryConfig := []byte(`<configuration><packageSources><clear /><add key="nuget.org" value="https://api.nuget.org/v3/index.json" /></packageSources></configuration>`)
+		err := os.WriteFile(filepath.Join(cfg.WorkingDirectory, "NuGet.Config"), repositoryConfig, 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var configContent []byte
+		runner := &FakeCommandRunner{RunFunc: func(command Command) error {
+			var err error
+			configContent, err = os.ReadFile(command.Args[len(command.Args)-1])
+			return err
+		}}
+
+		err = runAction(context.Background(), runner, cfg)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(runner.Commands) != 2 {
+			t.Fatalf("expected 2 commands, got %q", commandLines(runner))
+		}
+		configPath := runner.Commands[1].Args[len(runner.Commands[1].Args)-1]
+		if filepath.Dir(configPath) == cfg.WorkingDirectory {
+			t.Errorf("expected a temporary copy of the nuget config, got %v", configPath)
+		}
+		assertCommands(t, runner,
+			"dotnet nuget add source --name github-nuget https://nuget.pkg.github.com/acme/index.json --configfile "+configPath,
+			"NuGetPackageSourceCredentials_github-nuget=******** VSS_NUGET_EXTERNAL_FEED_ENDPOINTS=******** dotnet restore --packages .nuget/packages --configfile "+configPath)
+		if string(configContent) != string(repositoryConfig) {
+			t.Errorf("expected the copy to have the content of the repository config, got %v", string(configContent))
+		}
+		if _, err := os.Stat(configPath); !os.IsNotExist(err) {
+			t.Errorf("expected the temporary copy to be removed after the restore")
+		}
+	})
+
+	t.Run("ResolvesRelativeLocalFeedOfCommittedNugetConfig", func(t *testing.T) {
+		cfg := newTestConfig(t, "webservice")
+		cfg.Action = "restore"
+		cfg.Solution = nil
+		cfg.WorkingDirectory = t.TempDir()
+		cfg.NugetServerCredentialsJSONPath, _ = filepath.Abs("testdata/credentials/nuget_server.json")
+		err := os.WriteFile(filepath.Join(cfg.WorkingDirectory, "nuget.config"), []byte(`<configuration><packageSources><add key="local" value="./feed" /></packageSources></configuration>`), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var configContent []byte
+		runner := &FakeCommandRunner{RunFunc: func(command Command) error {
+			var err error
+			configContent, err = os.ReadFile(command.Args[len(command.Args)-1])
+			return err
+		}}
+
+		err = runAction(context.Background(), runner, cfg)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := `<add key="local" value="` + filepath.Join(cfg.WorkingDirectory, "feed") + `" />`
+		if !strings.Contains(string(configContent), expected) {
+			t.Errorf("expected the local feed to be resolved against the directory of the committed config, got %v", string(configContent))
+		}
+	})
+
+	t.Run("UsesNugetConfigOfParentDirectory", func(t *testing.T) {
+		cfg := newTestConfig(t, "webservice")
+		cfg.Action = "restore"
+		cfg.Solution = nil
+		root := t.TempDir()
+		cfg.WorkingDirectory = filepath.Join(root, "services", "Acme.Orders")
+		cfg.NugetServerCredentialsJSONPath, _ = filepath.Abs("testdata/credentials/nuget_server.json")
+		err := os.MkdirAll(cfg.WorkingDirectory, 0755)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = os.WriteFile(filepath.Join(root, "NuGet.config"), []byte(`<configuration><packageSources><add key="local" value="feed" /></packageSources></configuration>`), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var configContent []byte
+		runner := &FakeCommandRunner{RunFunc: func(command Command) error {
+			var err error
+			configContent, err = os.ReadFile(command.Args[len(command.Args)-1])
+			return err
+		}}
+
+		err = runAction(context.Background(), runner, cfg)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+		configPath := runner.Commands[0].Args[len(runner.Commands[0].Args)-1]
+		assertCommands(t, runner,
+			"dotnet nuget add source --name github-nuget https://nuget.pkg.github.com/acme/index.json --configfile "+configPath,
+			"NuGetPackageSourceCredentials_github-nuget=******** VSS_NUGET_EXTERNAL_FEED_ENDPOINTS=******** dotnet restore --packages .nuget/packages --configfile "+configPath)
+		expected := `<add key="local" value="` + filepath.Join(root, "feed") + `" />`
+		if !strings.Contains(string(configContent), expected) {
+			t.Errorf("expected a copy of the nuget config of the parent directory, got %v", string(configContent))
+		}
+	})
+
+	t.Run("AddsCredentialsToSourceOfCommittedNugetConfig", func(t *testing.T) {
+		cfg := newTestConfig(t, "webservice")
+		cfg.Action = "restore"
+		cfg.Solution = nil
+		cfg.WorkingDirectory = t.TempDir()
+		cfg.NugetServerCredentialsJSONPath, _ = filepath.Abs("testdata/credentials/nuget_server.json")
+		err := os.WriteFile(filepath.Join(cfg.WorkingDirectory, "nuget.config"), []byte(`<configuration><packageSources><add key="github" value="https://nuget.pkg.github.com/acme/index.json/" /></packageSources></configuration>`), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		runner := &FakeCommandRunner{}
+
+		err = runAction(context.Background(), runner, cfg)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+		configPath := runner.Commands[0].Args[len(runner.Commands[0].Args)-1]
+		assertCommands(t, runner,
+			"NuGetPackageSourceCredentials_github=******** VSS_NUGET_EXTERNAL_FEED_ENDPOINTS=******** dotnet restore --packages .nuget/packages --configfile "+configPath)
+	})
+
+	t.Run("UpdatesUrlOfSourceOfCommittedNugetConfigWithSameName", func(t *testing.T) {
+		cfg := newTestConfig(t, "webservice")
+		cfg.Action = "restore"
+		cfg.Solution = nil
+		cfg.WorkingDirectory = t.TempDir()
+		cfg.NugetServerCredentialsJSONPath, _ = filepath.Abs("testdata/credentials/nuget_server.json")
+		err := os.WriteFile(filepath.Join(cfg.WorkingDirectory, "nuget.config"), []byte(`<configuration><packageSources><add key="github-nuget" value="https://nuget.pkg.github.com/old/index.json" /></packageSources></configuration>`), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		runner := &FakeCommandRunner{}
+
+		err = runAction(context.Background(), runner, cfg)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+		configPath := runner.Commands[0].Args[len(runner.Commands[0].Args)-1]
+		assertCommands(t, runner,
+			"dotnet nuget update source github-nuget --source https://nuget.pkg.github.com/acme/index.json --configfile "+configPath,
+			"NuGetPackageSourceCredentials_github-nuget=******** VSS_NUGET_EXTERNAL_FEED_ENDPOINTS=******** dotnet restore --packages .nuget/packages --configfile "+configPath)
+	})
+
+	t.Run("UsesCommittedNugetConfigWithoutCredentials", func(t *testing.T) {
+		cfg := newTestConfig(t, "webservice")
+		cfg.Action = "restore"
+		cfg.Solution = nil
+		cfg.WorkingDirectory = t.TempDir()
+		err := os.WriteFile(filepath.Join(cfg.WorkingDirectory, "nuget.config"), []byte("<configuration />"), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		runner := &FakeCommandRunner{}
+
+		err = runAction(context.Background(), runner, cfg)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertCommands(t, runner, "dotnet restore --packages .nuget/packages")
+	})
+
+	t.Run("GeneratesNugetConfigWithPackageSourceMapping", func(t *testing.T) {
+		cfg := newTestConfig(t, "webservice")
+		cfg.Action = "restore"
+		cfg.NugetServerCredentialsJSONPath, _ = filepath.Abs("testdata/credentials/nuget_server.json")
+		cfg.PackageSourceMapping = `{"nuget.org": ["*"], "github-nuget": ["Acme.*"]}`
+		var configContent []byte
+		runner := &FakeCommandRunner{RunFunc: func(command Command) error {
+			var err error
+			configContent, err = os.ReadFile(command.Args[len(command.Args)-1])
+			return err
+		}}
+
+		err := runAction(context.Background(), runner, cfg)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+		configPath := runner.Commands[1].Args[len(runner.Commands[1].Args)-1]
+		assertCommands(t, runner,
+			"dotnet nuget add source --name github-nuget https://nuget.pkg.github.com/acme/index.json --configfile "+configPath,
+			"NuGetPackageSourceCredentials_github-nuget=******** VSS_NUGET_EXTERNAL_FEED_ENDPOINTS=******** dotnet restore Acme.FooApi.sln --packages .nuget/packages --configfile "+configPath)
+		expected := `<?xml version="1.0" encoding="utf-8"?>
+<configuration>
+  <packageSources>
+    <add key="nuget.org" value="https://api.nuget.org/v3/index.json" protocolVersion="3" />
+  </packageSources>
+  <packageSourceMapping>
+    <packageSource key="github-nuget">
+      <package pattern="Acme.*" />
+    </packageSource>
+    <packageSource key="nuget.org">
+      <package pattern="*" />
+    </packageSource>
+  </packageSourceMapping>
+</configuration>
+`
+		if string(configContent) != expected {
+			t.Errorf("unexpected generated nuget config:\n%v", string(configContent))
+		}
+	})
+
+	t.Run("FailsWhenPackageSourceMappingRefersToUnknownSource", func(t *testing.T) {
+		cfg := newTestConfig(t, "webservice")
+		cfg.Action = "restore"
+		cfg.PackageSourceMapping = `{"nuget.org": ["*"], "internal-nuget": ["Acme.*"]}`
+		runner := &FakeCommandRunner{}
+
+		err := runAction(context.Background(), runner, cfg)
+
+		if err == nil || !strings.Contains(err.Error(), "source(s) internal-nuget, which aren't any of the sources nuget.org") {
+			t.Fatalf("expected an error about the unknown source, got %v", err)
+		}
+		assertCommands(t, runner)
+	})
+
+	t.Run("FailsWhenCommittedNugetConfigHasPackageSourceMapping", func(t *testing.T) {
+		cfg := newTestConfig(t, "webservice")
+		cfg.Action = "restore"
+		cfg.Solution = nil
+		cfg.WorkingDirectory = t.TempDir()
+		cfg.PackageSourceMapping = `{"nuget.org": ["*"]}`
+		err := os.WriteFile(filepath.Join(cfg.WorkingDirectory, "nuget.config"), []byte(`<configuration><packageSourceMapping /></configuration>`), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		runner := &FakeCommandRunner{}
+
+		err = runAction(context.Background(), runner, cfg)
+
+		if err == nil {
+			t.Fatal("expected an error")
+		}
+		assertCommands(t, runner)
+	})
+
+	t.Run("FailsValidationForInvalidPackageSourceMapping", func(t *testing.T) {
+		cfg := newTestConfig(t, "webservice")
+		cfg.Action = "restore"
+		cfg.PackageSourceMapping = `nuget.org: "*"`
+
+		err := runAction(context.Background(), &FakeCommandRunner{}, cfg)
+
+		if err == nil || !strings.Contains(err.Error(), "invalid configuration for action restore") {
+			t.Fatalf("expected a validation error, got %v", err)
+		}
+	})
+
+	t.Run("RestoresInLockedMode", func(t *testing.T) {
+		cfg := newTestConfig(t, "locked")
+		cfg.Action = "restore"
+		cfg.Solution, _ = findSolution(cfg.WorkingDirectory, "Acme.Locked.App.slnf")
+		cfg.LockedMode = true
+		runner := &FakeCommandRunner{}
+
+		err := runAction(context.Background(), runner, cfg)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertCommands(t, runner, "dotnet restore Acme.Locked.App.slnf --packages .nuget/packages --locked-mode")
+	})
+
+	t.Run("FailsInLockedModeForProjectsWithoutLockFile", func(t *testing.T) {
+		cfg := newTestConfig(t, "locked")
+		cfg.Action = "restore"
+		cfg.LockedMode = true
+		runner := &FakeCommandRunner{}
+
+		err := runAction(context.Background(), runner, cfg)
+
+		if err == nil || !strings.Contains(err.Error(), "the project(s) src/Acme.Locked.Contracts/Acme.Locked.Contracts.csproj enable RestorePackagesWithLockFile but don't have a lock file") {
+			t.Fatalf("expected an error about the missing lock file, got %v", err)
+		}
+		assertCommands(t, runner)
+	})
+
+	t.Run("OnlyWarnsForProjectsWithoutLockFile", func(t *testing.T) {
+		cfg := newTestConfig(t, "locked")
+		cfg.Action = "restore"
+		runner := &FakeCommandRunner{}
+
+		err := runAction(context.Background(), runner, cfg)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertCommands(t, runner, "dotnet restore Acme.Locked.sln --packages .nuget/packages")
+	})
+
+	t.Run("ReportsDivergedPackagesWhenLockedModeFails", func(t *testing.T) {
+		cfg := newTestConfig(t, "locked")
+		cfg.Action = "restore"
+		cfg.Solution, _ = findSolution(cfg.WorkingDirectory, "Acme.Locked.App.slnf")
+		cfg.LockedMode = true
+		runner := &FakeCommandRunner{RunFunc: func(command Command) error {
+			return errors.New("exit status 1")
+		}}
+
+		err := runAction(context.Background(), runner, cfg)
+
+		if err == nil {
+			t.Fatal("expected an error")
+		}
+		for _, expected := range []string{
+			"src/Acme.Locked/Acme.Locked.csproj: Newtonsoft.Json is referenced as 13.0.3 but locked as [13.0.1, )",
+			"src/Acme.Locked/Acme.Locked.csproj: Serilog 3.1.1 is referenced but not in the lock file",
+			"src/Acme.Locked/Acme.Locked.csproj: Polly 8.2.0 is in the lock file but no longer referenced",
+		} {
+			if !strings.Contains(err.Error(), expected) {
+				t.Errorf("expected the error to contain %q, got %v", expected, err)
+			}
+		}
+	})
+
+	t.Run("ReportsLockFileErrorsOfDotnet", func(t *testing.T) {
+		err := describeLockFileDivergences(nil, "  Determining projects to restore...\n/src/Acme.Locked/Acme.Locked.csproj : error NU1004: The package reference Serilog version has changed from [3.0.0, ) to [3.1.1, ).\n", errors.New("exit status 1"))
+
+		if !strings.Contains(err.Error(), "\n  /src/Acme.Locked/Acme.Locked.csproj : error NU1004: The package reference Serilog version has changed") {
+			t.Errorf("expected the error to list the NU1004 errors, got %v", err)
+		}
+	})
+}
+
+func TestRestoreOffline(t *testing.T) {
+
+	t.Run("RestoresOnlyFromOfflineSource", func(t *testing.T) {
+		cfg := newTestConfig(t, "webservice")
+		cfg.Action = "restore"
+		cfg.NugetServerCredentialsJSONPath, _ = filepath.Abs("testdata/credentials/nuget_server.json")
+		cfg.Offline = true
+		cfg.OfflineSource = "/mirror/nuget"
+		var configContent []byte
+		runner := &FakeCommandRunner{RunFunc: func(command Command) error {
+			var err error
+			configContent, err = os.ReadFile(command.Args[len(command.Args)-1])
+			return err
+		}}
+
+		err := runAction(context.Background(), runner, cfg)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+		configPath := runner.Commands[0].Args[len(runner.Commands[0].Args)-1]
+		assertCommands(t, runner, "dotnet restore Acme.FooApi.sln --packages .nuget/packages --configfile "+configPath)
+		if !strings.Contains(string(configContent), "<clear />\n    <add key=\"offline\" value=\"/mirror/nuget\" />") {
+			t.Errorf("expected the offline source to be the only source, got %v", string(configContent))
+		}
+	})
+
+	t.Run("ListsPackagesMissingLocally", func(t *testing.T) {
+		cfg := newTestConfig(t, "webservice")
+		cfg.Action = "restore"
+		cfg.Solution = nil
+		cfg.WorkingDirectory = writeFiles(t, map[string]string{
+			"src/Acme.Api/Acme.Api.csproj": `<Project Sdk="Microsoft.NET.Sdk" />`,
+		})
+		cfg.Offline = true
+		cfg.PackageCacheDirectory = t.TempDir()
+		runner := &FakeCommandRunner{RunFunc: func(command Command) error {
+			assetsFilePath := filepath.Join(cfg.WorkingDirectory, "src/Acme.Api/obj/project.assets.json")
+			os.MkdirAll(filepath.Dir(assetsFilePath), 0755)
+			os.WriteFile(assetsFilePath, []byte(`{"version": 3, "project": {"frameworks": {"net8.0": {"dependencies": {"Serilog": {"version": "[3.1.1, )"}}}}}, "logs": [{"code": "NU1101", "message": "Unable to find package Serilog.", "libraryId": "Serilog"}]}`), 0644)
+			return errors.New("exit status 1")
+		}}
+
+		err := runAction(context.Background(), runner, cfg)
+
+		if err == nil || !strings.Contains(err.Error(), "mirror them to "+filepath.Join(cfg.WorkingDirectory, ".nuget/packages")+":\n  Serilog [3.1.1, )\n") {
+			t.Fatalf("expected an error listing the missing package, got %v", err)
+		}
+	})
+
+	t.Run("FailsWithoutLocalPackages", func(t *testing.T) {
+		cfg := newTestConfig(t, "webservice")
+		cfg.Action = "restore"
+		cfg.Offline = true
+		runner := &FakeCommandRunner{}
+
+		err := runAction(context.Background(), runner, cfg)
+
+		if err == nil || !strings.Contains(err.Error(), "offlineSource") {
+			t.Fatalf("expected an error about the missing local packages, got %v", err)
+		}
+		assertCommands(t, runner)
+	})
+}
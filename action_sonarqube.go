@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	foundation "github.com/estafette/estafette-foundation"
+	"github.com/rs/zerolog/log"
+)
+
+type analyzeSonarQubeAction struct{}
+
+func (a *analyzeSonarQubeAction) Name() string {
+	return "analyze-sonarqube"
+}
+
+func (a *analyzeSonarQubeAction) Description() string {
+	return "Runs the SonarQube analysis with coverage of the unit tests and sends the report to the SonarQube server."
+}
+
+func (a *analyzeSonarQubeAction) Labels() []string {
+	return []string{"sonarQubeServerUrl", "sonarQubeToken", "sonarQubeServerName", "sonarQubeCoverageExclusions"}
+}
+
+func (a *analyzeSonarQubeAction) Validate(cfg Config) error {
+	return validateConfiguration(cfg)
+}
+
+func (a *analyzeSonarQubeAction) Run(ctx context.Context, runner CommandRunner, cfg Config) error {
+
+	// Minimal example with defaults.
+	// image: extensions/dotnet:stable
+	// action: analyze-sonarqube
+
+	// Customizations.
+	// image: extensions/dotnet:stable
+	// action: analyze-sonarqube
+	// sonarQubeServerUrl: https://my-sonar-server.example.com
+	// sonarQubeCoverageExclusions: **Tests.cs
+
+	log.Printf("Running the SonarQube analysis...\n")
+
+	// Determine the SonarQube server credentials
+	// 1. If sonarQubeServerURL is explicitly specified, we use that.
+	// 2. If we have the default credentials from the server level, and sonarQubeServerName is explicitly specified, we look for the credential with the specified name.
+	// 3. If we have the default credentials from the server level, and sonarQubeServerName is not specified, we take the first credential. (This is the sensible default if we're using only one SonarQube server.)
+	if cfg.SonarQubeServerURL == "" {
+		if !foundation.FileExists(cfg.SonarQubeServerCredentialsJSONPath) {
+			return fmt.Errorf("the SonarQube server URL has to be specified to run the analysis")
+		}
+
+		var err error
+		cfg.SonarQubeServerURL, cfg.SonarQubeToken, err = GetSonarQubeServerCredentialsFromFile(cfg.SonarQubeServerCredentialsJSONPath, cfg.SonarQubeServerName)
+		if err != nil {
+			return err
+		}
+	}
+	cfg.Report.SetValue("sonarQubeServerUrl", cfg.SonarQubeServerURL)
+	if cfg.SonarQubeCoverageExclusions == "" {
+		cfg.SonarQubeCoverageExclusions = "**Tests.cs"
+	}
+
+	secrets := []string{cfg.SonarQubeToken}
+
+	// dotnet sonarscanner begin /k:"Travix.Core.ShoppingCart" /d:sonar.host.url=https://sonarqube.travix.com /d:sonar.cs.opencover.reportsPaths="**\coverage.opencover.xml" /d:sonar.coverage.exclusions="**Tests.cs"
+	args := []string{
+		"sonarscanner",
+		"begin",
+		fmt.Sprintf("/key:%s", cfg.SolutionName),
+		fmt.Sprintf("/d:sonar.host.url=%s", cfg.SonarQubeServerURL),
+		fmt.Sprintf("/d:sonar.login=%s", cfg.SonarQubeToken),
+		"/d:sonar.cs.opencover.reportsPaths=\"**\\coverage.opencover.xml\"",
+		fmt.Sprintf("/d:sonar.coverage.exclusions=\"%s\"", cfg.SonarQubeCoverageExclusions),
+	}
+
+	if cfg.BuildVersion != "" {
+		args = append(args, fmt.Sprintf("/version:%s", cfg.BuildVersion))
+	}
+
+	err := runner.Run(ctx, Command{Name: "dotnet", Args: args, Dir: cfg.WorkingDirectory, Secrets: secrets})
+	if err != nil {
+		return err
+	}
+
+	// dotnet build
+	args = appendSolutionArg([]string{"build"}, cfg)
+	args = appendVersionFlag(args, cfg)
+	args = appendSkipFlags(args, cfg, false)
+
+	restore, err := prepareImplicitRestore(ctx, runner, cfg)
+	if err != nil {
+		return err
+	}
+	defer restore.cleanup()
+	args = append(args, restore.args...)
+
+	err = runner.Run(ctx, Command{Name: "dotnet", Args: args, Env: restore.env, Dir: cfg.WorkingDirectory, Secrets: restore.secrets})
+	if err != nil {
+		return err
+	}
+
+	// Run unit tests with the extra arguments for coverage, a solution without unit tests can still be analyzed.
+	cfg.ForceBuild = true
+	cfg.RequireTests = false
+	err = runTests(ctx, runner, cfg, unitTests, "/p:CollectCoverage=true", "/p:CoverletOutputFormat=opencover", "/p:CopyLocalLockFileAssemblies=true")
+	if err != nil {
+		return err
+	}
+
+	// dotnet sonarscanner end
+	args = []string{
+		"sonarscanner",
+		"end",
+		fmt.Sprintf("/d:sonar.login=%s", cfg.SonarQubeToken),
+	}
+
+	return runner.Run(ctx, Command{Name: "dotnet", Args: args, Dir: cfg.WorkingDirectory, Secrets: secrets})
+}
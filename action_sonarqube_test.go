@@ -0,0 +1,26 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+func TestAnalyzeSonarQube(t *testing.T) {
+	cfg := newTestConfig(t, "webservice")
+	cfg.Action = "analyze-sonarqube"
+	cfg.SonarQubeServerCredentialsJSONPath, _ = filepath.Abs("testdata/credentials/sonarqube_server.json")
+	cfg.BuildVersion = "1.2.3"
+	runner := &FakeCommandRunner{}
+
+	err := runAction(context.Background(), runner, cfg)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertCommands(t, runner,
+		`dotnet sonarscanner begin /key:Acme.FooApi /d:sonar.host.url=https://sonarqube.acme.com /d:sonar.login=******** /d:sonar.cs.opencover.reportsPaths="**\coverage.opencover.xml" /d:sonar.coverage.exclusions="**Tests.cs" /version:1.2.3`,
+		"dotnet build Acme.FooApi.sln /p:Version=1.2.3 --no-restore",
+		"dotnet test --configuration Release --no-restore /p:CollectCoverage=true /p:CoverletOutputFormat=opencover /p:CopyLocalLockFileAssemblies=true test/Acme.FooApi.UnitTests/Acme.FooApi.UnitTests.csproj",
+		"dotnet sonarscanner end /d:sonar.login=********")
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func newTestConfig(t *testing.T, fixture string) Config {
+	t.Helper()
+
+	workingDir, err := filepath.Abs(filepath.Join("testdata", fixture))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	solution, err := findSolution(workingDir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return Config{
+		WorkingDirectory:                   workingDir,
+		SolutionName:                       solution.Name,
+		Solution:                           solution,
+		Configuration:                      "Release",
+		NugetServerCredentialsJSONPath:     filepath.Join(t.TempDir(), "nuget_server.json"),
+		NugetServerName:                    "github-nuget",
+		SonarQubeServerCredentialsJSONPath: filepath.Join(t.TempDir(), "sonarqube_server.json"),
+	}
+}
+
+func commandLines(runner *FakeCommandRunner) []string {
+	lines := []string{}
+	for _, c := range runner.Commands {
+		lines = append(lines, c.String())
+	}
+	return lines
+}
+
+func assertCommands(t *testing.T, runner *FakeCommandRunner, expected ...string) {
+	t.Helper()
+
+	actual := commandLines(runner)
+	expected = append([]string{}, expected...)
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("unexpected commands\nexpected:\n%q\nactual:\n%q", expected, actual)
+	}
+}
+
+func TestRunActionFailsOnUnknownAction(t *testing.T) {
+	cfg := newTestConfig(t, "webservice")
+	cfg.Action = "deploy"
+
+	err := runAction(context.Background(), &FakeCommandRunner{}, cfg)
+
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+func TestRunActionValidatesConfig(t *testing.T) {
+	cfg := newTestConfig(t, "webservice")
+	cfg.Action = "publish"
+	cfg.RuntimeIDs = []string{"linux-x64", "win-x64"}
+	runner := &FakeCommandRunner{}
+
+	err := runAction(context.Background(), runner, cfg)
+
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	assertCommands(t, runner)
+}
+
+func TestActionsHelp(t *testing.T) {
+	help := actionsHelp()
+
+	for _, name := range []string{"restore", "build", "test", "unit-test", "integration-test", "analyze-sonarqube", "publish", "pack", "push-nuget"} {
+		if getAction(name) == nil {
+			t.Errorf("action %v is not registered", name)
+		}
+		if !strings.Contains(help, "  "+name+": ") {
+			t.Errorf("action %v is missing from the help", name)
+		}
+	}
+	if !strings.Contains(help, "labels: outputFolder, project, publishReadyToRun, publishSingleFile, publishTrimmed, runtimeId") {
+		t.Errorf("the labels of the publish action are missing from the help:\n%v", help)
+	}
+}
+
+func TestPlanActionPlansSolutionsInOrder(t *testing.T) {
+	cfg := newTestConfig(t, "webservice")
+	cfg.Action = "ci"
+	cfg.Steps = []string{"restore", "build"}
+	cfg.AllSolutions = true
+	cfg.Parallelism = 4
+	cfg.DryRun = true
+	cfg.WorkingDirectory = newMonorepo(t, "services/Acme.Orders/Acme.Orders.sln", "libs/Acme.Common/Acme.Common.sln", "libs/Acme.Data/Acme.Data.sln")
+
+	commands, err := planAction(context.Background(), cfg)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	var dirs []string
+	for _, c := range commands {
+		dirs = append(dirs, relativePath(cfg.WorkingDirectory, c.Dir))
+	}
+	expected := []string{"libs/Acme.Common", "libs/Acme.Common", "libs/Acme.Data", "libs/Acme.Data", "services/Acme.Orders", "services/Acme.Orders"}
+	if !reflect.DeepEqual(dirs, expected) {
+		t.Errorf("unexpected order of the planned commands\nexpected:\n%q\nactual:\n%q", expected, dirs)
+	}
+}
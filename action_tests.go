@@ -0,0 +1,241 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	unitTests        = "unit"
+	integrationTests = "integration"
+)
+
+// testAction runs the tests of the test projects in the solution, limited to the ones of testType if it's set
+type testAction struct {
+	name        string
+	description string
+	testType    string
+}
+
+// testProject is a test project to run, with an optional filter to run only part of its tests
+type testProject struct {
+	path   string
+	filter string
+}
+
+func (a *testAction) Name() string {
+	return a.name
+}
+
+func (a *testAction) Description() string {
+	return a.description
+}
+
+func (a *testAction) Labels() []string {
+	if a.testType == "" {
+		return []string{"requireTests"}
+	}
+
+	return []string{"requireTests", "testTypeProperty", "integrationTestCategory"}
+}
+
+func (a *testAction) Validate(cfg Config) error {
+	return validateConfiguration(cfg)
+}
+
+func (a *testAction) Run(ctx context.Context, runner CommandRunner, cfg Config) error {
+	log.Printf("%v\n", a.description)
+
+	return runTests(ctx, runner, cfg, a.testType)
+}
+
+// Runs the tests for all test projects of the passed in test type, or for all test projects if the type is empty.
+func runTests(ctx context.Context, runner CommandRunner, cfg Config, testType string, extraArgs ...string) error {
+	// Minimal example with defaults.
+	// image: extensions/dotnet:stable
+	// action: test
+
+	// Customizations.
+	// image: extensions/dotnet:stable
+	// action: integration-test
+	// configuration: Debug
+	// forceBuild: true
+	// integrationTestCategory: Integration
+
+	args := []string{
+		"test",
+		"--configuration",
+		cfg.Configuration,
+	}
+
+	args = appendSkipFlags(args, cfg, true)
+	args = append(args, extraArgs...)
+
+	projects, err := getTestProjects(cfg, testType)
+	if err != nil {
+		return err
+	}
+
+	if len(projects) == 0 {
+		if cfg.RequireTests {
+			return fmt.Errorf("no test projects were found, %v; set requireTests: false if this is expected", describeTestSearch(cfg, testType))
+		}
+		log.Warn().Msgf("No test projects were found, %v.", describeTestSearch(cfg, testType))
+		return nil
+	}
+
+	restore, err := prepareImplicitRestore(ctx, runner, cfg)
+	if err != nil {
+		return err
+	}
+	defer restore.cleanup()
+	args = append(args, restore.args...)
+
+	// a project can have no tests of the test type, as long as the action runs any tests at all
+	var emptyProjects []string
+	for _, p := range projects {
+		log.Printf("Running tests for %s...\n", p.path)
+
+		argsForProject := make([]string, 0, len(args)+3)
+		argsForProject = append(argsForProject, args...)
+		if p.filter != "" {
+			argsForProject = append(argsForProject, "--filter", p.filter)
+		}
+		argsForProject = append(argsForProject, p.path)
+
+		var output bytes.Buffer
+		err := runner.Run(ctx, Command{Name: "dotnet", Args: argsForProject, Env: restore.env, Dir: cfg.WorkingDirectory, Secrets: restore.secrets, Output: &output})
+		if err != nil {
+			// failing tests make dotnet test exit with an error, but the counts are still worth reporting
+			cfg.Report.AddTestCounts(parseTestCounts(output.String()))
+			return err
+		}
+
+		cfg.Report.AddTestCounts(parseTestCounts(output.String()))
+
+		if noTestsFound(output.String()) {
+			log.Info().Msgf("The test project %v doesn't contain any tests to run.", p.path)
+			emptyProjects = append(emptyProjects, p.path)
+		}
+	}
+
+	if cfg.RequireTests && len(emptyProjects) == len(projects) {
+		if len(projects) == 1 {
+			return fmt.Errorf("the test project %v doesn't contain any tests to run; set requireTests: false if this is expected", projects[0].path)
+		}
+		return fmt.Errorf("none of the test projects %v contain any tests to run; set requireTests: false if this is expected", strings.Join(emptyProjects, ", "))
+	}
+
+	return nil
+}
+
+// Returns true if dotnet test reported that it didn't find any tests, which doesn't make it fail.
+func noTestsFound(output string) bool {
+	return strings.Contains(output, "No test is available in") || strings.Contains(output, "No test matches the given testcase filter")
+}
+
+// Returns a description of where test projects of the test type were searched for, to explain why none were found.
+func describeTestSearch(cfg Config, testType string) string {
+	kind := "test projects"
+	if testType != "" {
+		kind = fmt.Sprintf("projects with %v tests", testType)
+	}
+
+	if cfg.Solution == nil {
+		return fmt.Sprintf("there's no solution file in %v and the ./test folder doesn't contain %v", cfg.WorkingDirectory, kind)
+	}
+
+	names := []string{}
+	for _, p := range cfg.Solution.getProjects() {
+		names = append(names, p.Name)
+	}
+	if len(names) == 0 {
+		return fmt.Sprintf("the solution %v doesn't contain any projects", filepath.Base(cfg.Solution.Path))
+	}
+
+	return fmt.Sprintf("none of the projects %v in solution %v are %v", strings.Join(names, ", "), filepath.Base(cfg.Solution.Path), kind)
+}
+
+// Returns the test projects of the passed in test type.
+// The projects are taken from the solution, and without a solution file we fall back to the folders in ./test ending with UnitTests or IntegrationTests.
+func getTestProjects(cfg Config, testType string) ([]testProject, error) {
+	var projects []testProject
+
+	if cfg.Solution != nil {
+		for _, p := range cfg.Solution.getProjects() {
+			projectFile, err := cfg.Solution.readProjectFile(p)
+			if err != nil {
+				return nil, err
+			}
+			if projectFile == nil || !projectFile.IsTestProject() {
+				continue
+			}
+
+			project := testProject{path: cfg.Solution.getProjectPath(p, cfg.WorkingDirectory)}
+			if testType != "" {
+				projectTestType := getProjectTestType(cfg, p, projectFile)
+				switch {
+				case projectTestType == testType:
+				case projectTestType != "":
+					continue
+				case testType == integrationTests:
+					project.filter = fmt.Sprintf("Category=%v", cfg.IntegrationTestCategory)
+				default:
+					project.filter = fmt.Sprintf("Category!=%v", cfg.IntegrationTestCategory)
+				}
+			}
+
+			projects = append(projects, project)
+		}
+
+		return projects, nil
+	}
+
+	projectSuffix := ""
+	switch testType {
+	case unitTests:
+		projectSuffix = "UnitTests"
+	case integrationTests:
+		projectSuffix = "IntegrationTests"
+	}
+
+	files, err := os.ReadDir(filepath.Join(cfg.WorkingDirectory, "test"))
+
+	if err == nil {
+		for _, f := range files {
+			if f.IsDir() && strings.HasSuffix(f.Name(), projectSuffix) {
+				projects = append(projects, testProject{path: fmt.Sprintf("./test/%s", f.Name())})
+			}
+		}
+	} else if !os.IsNotExist(err) { // If we got an error just because the "test" folder doesn't exist, that's fine, we can ignore. We only fail with an error if it was something else.
+		return nil, fmt.Errorf("failed to read subdirectories under ./test: %w", err)
+	}
+
+	return projects, nil
+}
+
+// Returns whether a test project contains unit or integration tests.
+// 1. If the project sets the property named by testTypeProperty, we use its value.
+// 2. If integrationTestCategory is specified, the project can contain both, and we return an empty type so its tests get filtered on category.
+// 3. Otherwise a project with Integration in its name contains integration tests, and any other project unit tests.
+func getProjectTestType(cfg Config, p SolutionProject, projectFile *ProjectFile) string {
+	if value := projectFile.Properties[cfg.TestTypeProperty]; cfg.TestTypeProperty != "" && value != "" {
+		return strings.ToLower(value)
+	}
+
+	if cfg.IntegrationTestCategory != "" {
+		return ""
+	}
+
+	if strings.Contains(p.Name, "Integration") {
+		return integrationTests
+	}
+
+	return unitTests
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunTests(t *testing.T) {
+
+	t.Run("RunsEveryTestProjectInSolution", func(t *testing.T) {
+		cfg := newTestConfig(t, "webservice")
+		runner := &FakeCommandRunner{}
+
+		err := runTests(context.Background(), runner, cfg, "")
+
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertCommands(t, runner,
+			"dotnet test --configuration Release --no-restore --no-build test/Acme.FooApi.UnitTests/Acme.FooApi.UnitTests.csproj",
+			"dotnet test --configuration Release --no-restore --no-build test/Acme.FooApi.IntegrationTests/Acme.FooApi.IntegrationTests.csproj")
+	})
+
+	t.Run("RunsOnlyUnitTests", func(t *testing.T) {
+		cfg := newTestConfig(t, "webservice")
+		cfg.ForceBuild = true
+		runner := &FakeCommandRunner{}
+
+		err := runTests(context.Background(), runner, cfg, unitTests)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertCommands(t, runner, "dotnet test --configuration Release --no-restore test/Acme.FooApi.UnitTests/Acme.FooApi.UnitTests.csproj")
+	})
+
+	t.Run("RunsOnlyIntegrationTests", func(t *testing.T) {
+		cfg := newTestConfig(t, "webservice")
+		runner := &FakeCommandRunner{}
+
+		err := runTests(context.Background(), runner, cfg, integrationTests)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertCommands(t, runner, "dotnet test --configuration Release --no-restore --no-build test/Acme.FooApi.IntegrationTests/Acme.FooApi.IntegrationTests.csproj")
+	})
+
+	t.Run("DetectsTestProjectsRegardlessOfNaming", func(t *testing.T) {
+		cfg := newTestConfig(t, "nested")
+		runner := &FakeCommandRunner{}
+
+		err := runTests(context.Background(), runner, cfg, unitTests)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertCommands(t, runner, "dotnet test --configuration Release --no-restore --no-build tests/Acme.Shop.Api.Specs/Acme.Shop.Api.Specs.csproj")
+	})
+
+	t.Run("UsesTestTypeProperty", func(t *testing.T) {
+		cfg := newTestConfig(t, "categories")
+		cfg.TestTypeProperty = "TestType"
+		runner := &FakeCommandRunner{}
+
+		err := runTests(context.Background(), runner, cfg, integrationTests)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertCommands(t, runner, "dotnet test --configuration Release --no-restore --no-build Acme.Bar.Checks/Acme.Bar.Checks.csproj")
+	})
+
+	t.Run("FiltersOnIntegrationTestCategory", func(t *testing.T) {
+		cfg := newTestConfig(t, "categories")
+		cfg.TestTypeProperty = "TestType"
+		cfg.IntegrationTestCategory = "Integration"
+		runner := &FakeCommandRunner{}
+
+		err := runTests(context.Background(), runner, cfg, unitTests)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = runTests(context.Background(), runner, cfg, integrationTests)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assertCommands(t, runner,
+			"dotnet test --configuration Release --no-restore --no-build --filter Category!=Integration Acme.Bar.Tests/Acme.Bar.Tests.csproj",
+			"dotnet test --configuration Release --no-restore --no-build --filter Category=Integration Acme.Bar.Tests/Acme.Bar.Tests.csproj",
+			"dotnet test --configuration Release --no-restore --no-build Acme.Bar.Checks/Acme.Bar.Checks.csproj")
+	})
+
+	t.Run("FallsBackToTestFolderWithoutSolution", func(t *testing.T) {
+		cfg := newTestConfig(t, "webservice")
+		cfg.WorkingDirectory = t.TempDir()
+		cfg.Solution = nil
+		for _, dir := range []string{"Acme.FooApi.UnitTests", "Acme.FooApi.IntegrationTests"} {
+			err := os.MkdirAll(filepath.Join(cfg.WorkingDirectory, "test", dir), 0755)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		runner := &FakeCommandRunner{}
+
+		err := runTests(context.Background(), runner, cfg, unitTests)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertCommands(t, runner, "dotnet test --configuration Release --no-restore --no-build ./test/Acme.FooApi.UnitTests")
+	})
+}
+
+func TestRunTestsRequiresTests(t *testing.T) {
+
+	t.Run("FailsWithoutTestProjects", func(t *testing.T) {
+		cfg := newTestConfig(t, "library")
+		cfg.RequireTests = true
+		runner := &FakeCommandRunner{}
+
+		err := runTests(context.Background(), runner, cfg, unitTests)
+
+		if err == nil || !strings.Contains(err.Error(), "none of the projects Acme.Lib in solution Acme.Lib.sln are projects with unit tests") {
+			t.Fatalf("expected an error listing the searched projects, got %v", err)
+		}
+		assertCommands(t, runner)
+	})
+
+	t.Run("SucceedsWithoutTestProjectsIfNotRequired", func(t *testing.T) {
+		cfg := newTestConfig(t, "library")
+		runner := &FakeCommandRunner{}
+
+		err := runTests(context.Background(), runner, cfg, "")
+
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertCommands(t, runner)
+	})
+
+	t.Run("FailsWhenProjectHasNoTests", func(t *testing.T) {
+		cfg := newTestConfig(t, "webservice")
+		cfg.RequireTests = true
+		runner := &FakeCommandRunner{
+			RunFunc: func(command Command) error {
+				fmt.Fprintf(command.Output, "No test is available in /build/test/Acme.FooApi.UnitTests/bin/Release/net8.0/Acme.FooApi.UnitTests.dll. Make sure that test discoverer & executors are registered and platform & framework version settings are appropriate and try again.\n")
+				return nil
+			},
+		}
+
+		err := runTests(context.Background(), runner, cfg, unitTests)
+
+		if err == nil || !strings.Contains(err.Error(), "test/Acme.FooApi.UnitTests/Acme.FooApi.UnitTests.csproj doesn't contain any tests") {
+			t.Fatalf("expected an error for the empty test project, got %v", err)
+		}
+	})
+
+	t.Run("SucceedsWhenOtherProjectsRanTests", func(t *testing.T) {
+		cfg := newTestConfig(t, "webservice")
+		cfg.RequireTests = true
+		runner := &FakeCommandRunner{
+			RunFunc: func(command Command) error {
+				if strings.Contains(command.String(), "IntegrationTests") {
+					fmt.Fprintf(command.Output, "No test matches the given testcase filter `Category!=Integration` in /build/test/Acme.FooApi.IntegrationTests/bin/Release/net8.0/Acme.FooApi.IntegrationTests.dll\n")
+				}
+				return nil
+			},
+		}
+
+		err := runTests(context.Background(), runner, cfg, "")
+
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("FailsWhenNoProjectRanTests", func(t *testing.T) {
+		cfg := newTestConfig(t, "webservice")
+		cfg.RequireTests = true
+		runner := &FakeCommandRunner{
+			RunFunc: func(command Command) error {
+				fmt.Fprintf(command.Output, "No test matches the given testcase filter `Category!=Integration` in /build/test/Acme.FooApi.Tests.dll\n")
+				return nil
+			},
+		}
+
+		err := runTests(context.Background(), runner, cfg, "")
+
+		if err == nil || !strings.Contains(err.Error(), "none of the test projects") {
+			t.Fatalf("expected an error for the test projects without tests, got %v", err)
+		}
+		if len(runner.Commands) != 2 {
+			t.Errorf("expected every test project to run, got %q", commandLines(runner))
+		}
+	})
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	foundation "github.com/estafette/estafette-foundation"
+)
+
+// ProjectAssetsFile is the obj/project.assets.json file, in which NuGet records the result of the restore of a project
+type ProjectAssetsFile struct {
+	Version int `json:"version"`
+	// Targets holds the resolved packages per target framework, and per target framework and runtime identifier like net8.0/linux-x64
+	Targets map[string]json.RawMessage `json:"targets"`
+	// Libraries holds the resolved packages and projects by name and version, like Serilog/3.1.1
+	Libraries map[string]AssetsLibrary `json:"libraries"`
+	// PackageFolders are the folders the packages were resolved from, the packages folder of the restore and the fallback folders
+	PackageFolders map[string]json.RawMessage `json:"packageFolders"`
+	Project        AssetsProject              `json:"project"`
+	Logs           []AssetsLogMessage         `json:"logs"`
+}
+
+// AssetsLibrary is a resolved package or project in an assets file
+type AssetsLibrary struct {
+	Type string `json:"type"`
+	// Path is the directory of the package relative to the package folders, like serilog/3.1.1
+	Path string `json:"path"`
+}
+
+// AssetsProject holds the restore inputs of the project in an assets file
+type AssetsProject struct {
+	Frameworks map[string]struct {
+		Dependencies map[string]struct {
+			Version string `json:"version"`
+		} `json:"dependencies"`
+	} `json:"frameworks"`
+}
+
+// AssetsLogMessage is an error or warning of the restore in an assets file
+type AssetsLogMessage struct {
+	Code      string `json:"code"`
+	Level     string `json:"level"`
+	Message   string `json:"message"`
+	LibraryID string `json:"libraryId"`
+}
+
+// ReadProjectAssetsFile reads a project.assets.json file
+func ReadProjectAssetsFile(assetsFilePath string) (*ProjectAssetsFile, error) {
+	content, err := os.ReadFile(assetsFilePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed reading assets file %v: %w", assetsFilePath, err)
+	}
+
+	var assetsFile ProjectAssetsFile
+	err = json.Unmarshal(content, &assetsFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed parsing assets file %v: %w", assetsFilePath, err)
+	}
+
+	return &assetsFile, nil
+}
+
+// Returns the path of the assets file of the project, which is either a project file or the directory containing it.
+func getProjectAssetsFilePath(projectPath string) string {
+	dir := projectPath
+	if isReadableProjectFile(projectPath) {
+		dir = filepath.Dir(projectPath)
+	}
+
+	return filepath.Join(dir, "obj", "project.assets.json")
+}
+
+// Returns the target frameworks which were restored without the runtime identifier.
+func (a *ProjectAssetsFile) getFrameworksMissingRuntime(runtimeID string) []string {
+	var missing []string
+	for target := range a.Targets {
+		if strings.Contains(target, "/") {
+			continue
+		}
+		if _, ok := a.Targets[target+"/"+runtimeID]; !ok {
+			missing = append(missing, target)
+		}
+	}
+	sort.Strings(missing)
+
+	return missing
+}
+
+// Returns the packages, with their versions, which aren't in any of the local package folders, either because the restore couldn't find them or because they were resolved from elsewhere.
+func (a *ProjectAssetsFile) getMissingPackages() []string {
+	var missing []string
+	for name, library := range a.Libraries {
+		if library.Type != "package" {
+			continue
+		}
+
+		id, version, _ := strings.Cut(name, "/")
+		found := false
+		for folder := range a.PackageFolders {
+			dir := filepath.Join(folder, filepath.FromSlash(library.Path))
+			// NuGet writes these files last when it extracts a package, so a package without them is incomplete
+			found = found || foundation.FileExists(filepath.Join(dir, ".nupkg.metadata")) || foundation.FileExists(filepath.Join(dir, strings.ToLower(id+"."+version)+".nupkg.sha512"))
+		}
+		if !found {
+			missing = append(missing, id+" "+version)
+		}
+	}
+
+	// packages the restore couldn't find at all aren't in the libraries, but in the logs
+	for _, message := range a.Logs {
+		switch message.Code {
+		case "NU1101", "NU1102", "NU1103":
+			version := a.getRequestedVersion(message.LibraryID)
+			if version == "" {
+				missing = append(missing, fmt.Sprintf("%v (%v)", message.LibraryID, message.Message))
+				continue
+			}
+			missing = append(missing, message.LibraryID+" "+version)
+		}
+	}
+
+	missing = dedupe(missing)
+	sort.Strings(missing)
+
+	return missing
+}
+
+// Returns the version range the project requests of the package, or an empty string for a transitive package.
+func (a *ProjectAssetsFile) getRequestedVersion(packageID string) string {
+	for _, framework := range a.Project.Frameworks {
+		for name, dependency := range framework.Dependencies {
+			if strings.EqualFold(name, packageID) {
+				return dependency.Version
+			}
+		}
+	}
+
+	return ""
+}
+
+// Returns the assets files of the projects of the solution, or of all projects under the working directory without a solution, leaving out the projects which weren't restored.
+func getProjectAssetsFilePaths(solution *Solution, workingDir string) ([]string, error) {
+	var projectPaths []string
+	if solution != nil {
+		for _, p := range solution.getProjects() {
+			projectPaths = append(projectPaths, filepath.Join(solution.Dir, filepath.FromSlash(p.Path)))
+		}
+	} else {
+		err := filepath.WalkDir(workingDir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() && path != workingDir && (strings.HasPrefix(d.Name(), ".") || foundation.StringArrayContains(skippedDirectories, d.Name())) {
+				return filepath.SkipDir
+			}
+			if !d.IsDir() && isReadableProjectFile(path) {
+				projectPaths = append(projectPaths, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed finding the projects in %v: %w", workingDir, err)
+		}
+	}
+
+	var assetsFilePaths []string
+	for _, projectPath := range projectPaths {
+		if assetsFilePath := getProjectAssetsFilePath(projectPath); foundation.FileExists(assetsFilePath) {
+			assetsFilePaths = append(assetsFilePaths, assetsFilePath)
+		}
+	}
+
+	return assetsFilePaths, nil
+}
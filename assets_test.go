@@ -0,0 +1,70 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestProjectAssetsFile(t *testing.T) {
+
+	t.Run("ReturnsFrameworksMissingRuntime", func(t *testing.T) {
+		root := writeFiles(t, map[string]string{
+			"src/Acme.Api/obj/project.assets.json": `{"version": 3, "targets": {"net8.0": {}, "net8.0/linux-x64": {}, "net9.0": {}}}`,
+		})
+
+		assetsFile, err := ReadProjectAssetsFile(getProjectAssetsFilePath(filepath.Join(root, "src/Acme.Api/Acme.Api.csproj")))
+
+		if err != nil {
+			t.Fatal(err)
+		}
+		if missing := assetsFile.getFrameworksMissingRuntime("linux-x64"); strings.Join(missing, ",") != "net9.0" {
+			t.Errorf("expected net9.0 to miss the runtime, got %v", missing)
+		}
+		if missing := assetsFile.getFrameworksMissingRuntime("win-x64"); strings.Join(missing, ",") != "net8.0,net9.0" {
+			t.Errorf("expected both frameworks to miss the runtime, got %v", missing)
+		}
+	})
+
+	t.Run("ReturnsPackagesMissingLocally", func(t *testing.T) {
+		packagesFolder := writeFiles(t, map[string]string{
+			"serilog/3.1.1/serilog.3.1.1.nupkg.sha512":      "hash",
+			"polly/8.2.0/polly.8.2.0.nuspec":                "<package />",
+			"newtonsoft.json/13.0.3/newtonsoft.json.nuspec": "<package />",
+			"newtonsoft.json/13.0.3/.nupkg.metadata":        "{}",
+		})
+		root := writeFiles(t, map[string]string{
+			"obj/project.assets.json": `{
+  "version": 3,
+  "libraries": {
+    "Serilog/3.1.1": {"type": "package", "path": "serilog/3.1.1"},
+    "Polly/8.2.0": {"type": "package", "path": "polly/8.2.0"},
+    "Newtonsoft.Json/13.0.3": {"type": "package", "path": "newtonsoft.json/13.0.3"},
+    "Acme.Contracts/1.0.0": {"type": "project", "path": "../Acme.Contracts/Acme.Contracts.csproj"}
+  },
+  "packageFolders": {"` + filepath.ToSlash(packagesFolder) + `/": {}},
+  "project": {"frameworks": {"net8.0": {"dependencies": {"Acme.Internal": {"version": "[2.0.0, )"}}}}},
+  "logs": [
+    {"code": "NU1101", "level": "Error", "message": "Unable to find package Acme.Internal.", "libraryId": "Acme.Internal"},
+    {"code": "NU1102", "level": "Error", "message": "Unable to find package Polly.Core with version (>= 8.2.0)", "libraryId": "Polly.Core"},
+    {"code": "NU1603", "level": "Warning", "message": "Serilog 3.1.0 was not found.", "libraryId": "Serilog"}
+  ]
+}`,
+		})
+		assetsFile, err := ReadProjectAssetsFile(filepath.Join(root, "obj/project.assets.json"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		missing := assetsFile.getMissingPackages()
+
+		expected := []string{
+			"Acme.Internal [2.0.0, )",
+			"Polly 8.2.0",
+			"Polly.Core (Unable to find package Polly.Core with version (>= 8.2.0))",
+		}
+		if strings.Join(missing, "\n") != strings.Join(expected, "\n") {
+			t.Errorf("unexpected missing packages\nexpected:\n%v\nactual:\n%v", strings.Join(expected, "\n"), strings.Join(missing, "\n"))
+		}
+	})
+}
@@ -0,0 +1,312 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	foundation "github.com/estafette/estafette-foundation"
+	"github.com/rs/zerolog/log"
+)
+
+// the directory, relative to the working directory, the packages are restored into
+const packagesDirectory = ".nuget/packages"
+
+// the files MSBuild imports into every project below them, which can add package references or set their versions
+var msbuildDirectoryFiles = []string{"Directory.Build.props", "Directory.Build.targets", "Directory.Packages.props"}
+
+// Returns the key of the package cache, which is a hash of everything that determines the restored packages:
+// the lock files, the package references of the project files, the Directory.Build.props, Directory.Build.targets and Directory.Packages.props files,
+// also the ones in the parent directories which MSBuild imports as well, and the runtime identifiers.
+func computePackageCacheKey(root string, runtimeIDs []string) (string, error) {
+	var inputs []string
+
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != root && (strings.HasPrefix(d.Name(), ".") || foundation.StringArrayContains(skippedDirectories, d.Name())) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		relative := relativePath(root, path)
+		switch {
+		case d.Name() == "packages.lock.json" || foundation.StringArrayContains(msbuildDirectoryFiles, d.Name()):
+			content, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			inputs = append(inputs, relative+"\n"+string(content))
+		case isReadableProjectFile(path):
+			projectFile, err := ReadProjectFile(path)
+			if err != nil {
+				return err
+			}
+			// only the package references matter, so other changes to the project don't invalidate the cache
+			var references []string
+			for _, reference := range projectFile.PackageReferences {
+				references = append(references, reference.Include+" "+reference.Version)
+			}
+			inputs = append(inputs, relative+"\n"+strings.Join(references, "\n"))
+		}
+
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed computing the package cache key for %v: %w", root, err)
+	}
+
+	for dir := filepath.Dir(root); ; dir = filepath.Dir(dir) {
+		for _, name := range msbuildDirectoryFiles {
+			path := filepath.Join(dir, name)
+			if !foundation.FileExists(path) {
+				continue
+			}
+			content, err := os.ReadFile(path)
+			if err != nil {
+				return "", fmt.Errorf("failed computing the package cache key for %v: %w", root, err)
+			}
+			inputs = append(inputs, relativePath(root, path)+"\n"+string(content))
+		}
+		if filepath.Dir(dir) == dir {
+			break
+		}
+	}
+
+	if len(runtimeIDs) > 0 {
+		inputs = append(inputs, "runtimes\n"+strings.Join(runtimeIDs, "\n"))
+	}
+
+	sort.Strings(inputs)
+
+	hash := sha256.New()
+	for _, input := range inputs {
+		io.WriteString(hash, input)
+		io.WriteString(hash, "\n\x00\n")
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// Extracts the cached packages into the packages directory if the cache has them, and otherwise returns the path of the archive to save them to after the restore.
+// A broken cache only slows down the restore, so it's logged as a warning instead of failing the action.
+func loadPackageCache(cfg Config) string {
+	key, err := computePackageCacheKey(cfg.WorkingDirectory, cfg.RuntimeIDs)
+	if err != nil {
+		log.Warn().Err(err).Msg("Not using the package cache.")
+		return ""
+	}
+	cfg.Report.SetValue("packageCacheKey", key)
+
+	cacheDir := cfg.PackageCacheDirectory
+	if !filepath.IsAbs(cacheDir) {
+		cacheDir = filepath.Join(cfg.WorkingDirectory, cacheDir)
+	}
+
+	archivePath := getPackageCacheArchivePath(cacheDir, key)
+	info, err := os.Stat(archivePath)
+	if err != nil {
+		log.Info().Msgf("Package cache miss for key %v.", key)
+		cfg.Report.SetValue("packageCacheHit", "false")
+		if cfg.DryRun {
+			return ""
+		}
+		return archivePath
+	}
+
+	log.Info().Msgf("Package cache hit for key %v, extracting %v...", key, formatSize(info.Size()))
+	cfg.Report.SetValue("packageCacheHit", "true")
+	if cfg.DryRun {
+		return ""
+	}
+
+	err = extractDirectoryArchive(archivePath, filepath.Join(cfg.WorkingDirectory, packagesDirectory))
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed extracting the cached packages, they'll be restored from the sources instead.")
+	}
+
+	return ""
+}
+
+// Saves the restored packages to the archive in the package cache.
+func savePackageCache(cfg Config, archivePath string) {
+	size, err := writeDirectoryArchive(filepath.Join(cfg.WorkingDirectory, packagesDirectory), archivePath)
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed saving the packages to the cache.")
+		return
+	}
+
+	log.Info().Msgf("Saved the packages to the cache as %v (%v).", archivePath, formatSize(size))
+}
+
+// Returns the path of the archive of the packages with the key in the cache directory.
+func getPackageCacheArchivePath(cacheDir, key string) string {
+	return filepath.Join(cacheDir, fmt.Sprintf("nuget-packages-%v.tar.gz", key))
+}
+
+// Writes the content of the directory to a gzip compressed tar archive, through a temporary file so a concurrent build never reads a partial archive.
+// It returns the size of the archive.
+func writeDirectoryArchive(dir, archivePath string) (int64, error) {
+	err := os.MkdirAll(filepath.Dir(archivePath), 0755)
+	if err != nil {
+		return 0, fmt.Errorf("failed creating the cache directory %v: %w", filepath.Dir(archivePath), err)
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(archivePath), filepath.Base(archivePath)+".*.tmp")
+	if err != nil {
+		return 0, fmt.Errorf("failed creating archive %v: %w", archivePath, err)
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	gzipWriter := gzip.NewWriter(file)
+	tarWriter := tar.NewWriter(gzipWriter)
+
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == dir || (!d.IsDir() && !d.Type().IsRegular()) {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		header, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		header.Name = relativePath(dir, path)
+		if d.IsDir() {
+			header.Name += "/"
+		}
+
+		err = tarWriter.WriteHeader(header)
+		if err != nil || d.IsDir() {
+			return err
+		}
+
+		content, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer content.Close()
+
+		_, err = io.Copy(tarWriter, content)
+		return err
+	})
+	if err == nil {
+		err = tarWriter.Close()
+	}
+	if err == nil {
+		err = gzipWriter.Close()
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed writing archive %v: %w", archivePath, err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		return 0, err
+	}
+	err = file.Close()
+	if err != nil {
+		return 0, err
+	}
+
+	err = os.Rename(file.Name(), archivePath)
+	if err != nil {
+		return 0, fmt.Errorf("failed writing archive %v: %w", archivePath, err)
+	}
+
+	return info.Size(), nil
+}
+
+// Extracts a gzip compressed tar archive into the directory.
+func extractDirectoryArchive(archivePath, dir string) error {
+	file, err := os.Open(archivePath)
+	if err != nil {
+		return fmt.Errorf("failed opening archive %v: %w", archivePath, err)
+	}
+	defer file.Close()
+
+	gzipReader, err := gzip.NewReader(file)
+	if err != nil {
+		return fmt.Errorf("failed reading archive %v: %w", archivePath, err)
+	}
+	tarReader := tar.NewReader(gzipReader)
+
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed reading archive %v: %w", archivePath, err)
+		}
+
+		target := filepath.Join(dir, filepath.FromSlash(header.Name))
+		if !strings.HasPrefix(target, filepath.Clean(dir)+string(os.PathSeparator)) {
+			return fmt.Errorf("archive %v contains %v, which is outside of the directory it's extracted to", archivePath, header.Name)
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			err = os.MkdirAll(target, 0755)
+		case tar.TypeReg:
+			err = extractFile(tarReader, target, header.FileInfo().Mode())
+		}
+		if err != nil {
+			return fmt.Errorf("failed extracting %v from archive %v: %w", header.Name, archivePath, err)
+		}
+	}
+}
+
+func extractFile(r io.Reader, target string, mode fs.FileMode) error {
+	err := os.MkdirAll(filepath.Dir(target), 0755)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(target, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, mode.Perm())
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = io.Copy(file, r)
+	if err != nil {
+		return err
+	}
+
+	return file.Close()
+}
+
+// Returns a size in bytes as a human readable string, like 12.3 MB.
+func formatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f %cB", float64(size)/float64(div), "KMGTPE"[exp])
+}
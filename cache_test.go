@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	foundation "github.com/estafette/estafette-foundation"
+)
+
+// Writes the files, relative to a new temporary directory, and returns that directory.
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	root := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		err := os.MkdirAll(filepath.Dir(path), 0755)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = os.WriteFile(path, []byte(content), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return root
+}
+
+func TestComputePackageCacheKey(t *testing.T) {
+	project := `<Project Sdk="Microsoft.NET.Sdk"><PropertyGroup><TargetFramework>%v</TargetFramework></PropertyGroup><ItemGroup><PackageReference Include="Serilog" Version="%v" /></ItemGroup></Project>`
+	newKey := func(targetFramework, version string, extra map[string]string) string {
+		files := map[string]string{"src/Acme.Lib/Acme.Lib.csproj": fmt.Sprintf(project, targetFramework, version)}
+		for name, content := range extra {
+			files[name] = content
+		}
+		key, err := computePackageCacheKey(writeFiles(t, files), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return key
+	}
+
+	key := newKey("net8.0", "3.1.1", nil)
+
+	if newKey("net8.0", "3.1.1", nil) != key {
+		t.Errorf("expected the same key for the same package references")
+	}
+	if newKey("net9.0", "3.1.1", nil) != key {
+		t.Errorf("expected the same key when only other properties change")
+	}
+	if newKey("net8.0", "3.1.1", map[string]string{"bin/Release/obj.csproj": "<Project />", ".nuget/packages/serilog/serilog.nuspec": "<package />"}) != key {
+		t.Errorf("expected the same key when only build output and restored packages change")
+	}
+	if newKey("net8.0", "4.0.0", nil) == key {
+		t.Errorf("expected another key when a package reference changes")
+	}
+	if newKey("net8.0", "3.1.1", map[string]string{"Directory.Packages.props": "<Project />"}) == key {
+		t.Errorf("expected another key with central package versions")
+	}
+	if newKey("net8.0", "3.1.1", map[string]string{"src/Acme.Lib/packages.lock.json": "{}"}) == key {
+		t.Errorf("expected another key with a lock file")
+	}
+	for _, name := range []string{"Directory.Build.props", "src/Directory.Build.targets"} {
+		if newKey("net8.0", "3.1.1", map[string]string{name: `<Project><ItemGroup><PackageReference Include="StyleCop.Analyzers" Version="1.1.118" /></ItemGroup></Project>`}) == key {
+			t.Errorf("expected another key with package references in %v", name)
+		}
+	}
+
+	newParentKey := func(buildProps string) string {
+		repository := writeFiles(t, map[string]string{
+			"services/Acme.Orders/Acme.Orders.csproj": fmt.Sprintf(project, "net8.0", "3.1.1"),
+			"Directory.Build.props":                   buildProps,
+		})
+		key, err := computePackageCacheKey(filepath.Join(repository, "services", "Acme.Orders"), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return key
+	}
+	if newParentKey(`<Project><ItemGroup><PackageReference Include="Serilog" Version="3.1.1" /></ItemGroup></Project>`) == newParentKey(`<Project><ItemGroup><PackageReference Include="Serilog" Version="4.0.0" /></ItemGroup></Project>`) {
+		t.Errorf("expected another key when the Directory.Build.props of a parent directory changes")
+	}
+
+	root := writeFiles(t, map[string]string{"src/Acme.Lib/Acme.Lib.csproj": fmt.Sprintf(project, "net8.0", "3.1.1")})
+	withoutRuntime, _ := computePackageCacheKey(root, nil)
+	withRuntime, _ := computePackageCacheKey(root, []string{"linux-x64"})
+	if withRuntime == withoutRuntime {
+		t.Errorf("expected another key when restoring for a runtime")
+	}
+}
+
+func TestDirectoryArchive(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"serilog/3.1.1/serilog.nuspec":         "<package />",
+		"serilog/3.1.1/lib/net8.0/Serilog.dll": "binary",
+	})
+	archivePath := filepath.Join(t.TempDir(), "cache", "packages.tar.gz")
+
+	size, err := writeDirectoryArchive(dir, archivePath)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size == 0 {
+		t.Errorf("expected the size of the archive")
+	}
+
+	target := filepath.Join(t.TempDir(), ".nuget", "packages")
+	err = extractDirectoryArchive(archivePath, target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err := os.ReadFile(filepath.Join(target, "serilog", "3.1.1", "lib", "net8.0", "Serilog.dll"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "binary" {
+		t.Errorf("unexpected content %v", string(content))
+	}
+}
+
+func TestRestoreWithPackageCache(t *testing.T) {
+	cacheDir := t.TempDir()
+	newConfig := func() Config {
+		cfg := newTestConfig(t, "webservice")
+		cfg.Action = "restore"
+		cfg.Solution = nil
+		cfg.WorkingDirectory = writeFiles(t, map[string]string{"src/Acme.Lib/Acme.Lib.csproj": `<Project Sdk="Microsoft.NET.Sdk"><ItemGroup><PackageReference Include="Serilog" Version="3.1.1" /></ItemGroup></Project>`})
+		cfg.PackageCacheDirectory = cacheDir
+		cfg.Report = NewReport(cfg)
+		return cfg
+	}
+
+	// the first build restores the packages from the sources and saves them to the cache
+	cfg := newConfig()
+	runner := &FakeCommandRunner{RunFunc: func(command Command) error {
+		path := filepath.Join(command.Dir, ".nuget", "packages", "serilog", "3.1.1", "serilog.nuspec")
+		err := os.MkdirAll(filepath.Dir(path), 0755)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(path, []byte("<package />"), 0644)
+	}}
+
+	err := runAction(context.Background(), runner, cfg)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Report.Values["packageCacheHit"] != "false" {
+		t.Errorf("expected a cache miss, got %v", cfg.Report.Values)
+	}
+	if !foundation.FileExists(getPackageCacheArchivePath(cacheDir, cfg.Report.Values["packageCacheKey"])) {
+		t.Fatalf("expected the packages to be saved to the cache")
+	}
+
+	// the next build gets the packages from the cache before restoring
+	cfg = newConfig()
+
+	err = runAction(context.Background(), &FakeCommandRunner{}, cfg)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Report.Values["packageCacheHit"] != "true" {
+		t.Errorf("expected a cache hit, got %v", cfg.Report.Values)
+	}
+	if !foundation.FileExists(filepath.Join(cfg.WorkingDirectory, ".nuget", "packages", "serilog", "3.1.1", "serilog.nuspec")) {
+		t.Errorf("expected the cached packages to be extracted")
+	}
+}
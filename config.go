@@ -0,0 +1,193 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// Config holds the resolved labels of this step, so actions don't depend on the global flags
+type Config struct {
+	Action                             string
+	WorkingDirectory                   string
+	SolutionName                       string
+	AllSolutions                       bool
+	Parallelism                        int
+	SolutionFile                       string
+	Solution                           *Solution
+	Configuration                      string
+	BuildVersion                       string
+	Project                            string
+	RuntimeIDs                         []string
+	ForceRestore                       bool
+	ForceBuild                         bool
+	OutputFolder                       string
+	PackagesFolder                     string
+	NugetSources                       string
+	NugetServerURL                     string
+	NugetServerAPIKey                  string
+	NugetServerCredentialsJSONPath     string
+	NugetServerName                    string
+	NugetServerNames                   []string
+	NugetServerUsername                string
+	NugetSourceName                    string
+	NugetPushServerNames               []string
+	NugetPushPolicy                    string
+	NugetPackageRules                  []string
+	NugetPackageInclude                []string
+	NugetPackageExclude                []string
+	NugetSymbolServerName              string
+	NugetExistingVersionPolicy         string
+	NugetSkipDuplicate                 bool
+	PackageSourceMapping               string
+	LockedMode                         bool
+	PackageCacheDirectory              string
+	Offline                            bool
+	MaxRetries                         int
+	RetryDelay                         time.Duration
+	OfflineSource                      string
+	IncludeSymbols                     bool
+	SymbolPackageFormat                string
+	PublishReadyToRun                  bool
+	PublishSingleFile                  bool
+	PublishTrimmed                     bool
+	SonarQubeServerURL                 string
+	SonarQubeToken                     string
+	SonarQubeServerCredentialsJSONPath string
+	SonarQubeServerName                string
+	SonarQubeCoverageExclusions        string
+	RequireTests                       bool
+	TestTypeProperty                   string
+	IntegrationTestCategory            string
+	DryRun                             bool
+	Steps                              []string
+	ReportPath                         string
+	// Report collects the executed commands and resolved values, it's nil when nothing is reported like in a dry run
+	Report *Report
+}
+
+// the value of the reportPath label which skips writing the report
+const noReportPath = "none"
+
+func newConfigFromFlags(workingDir string) Config {
+	cfg := Config{
+		Action:                             *action,
+		WorkingDirectory:                   workingDir,
+		AllSolutions:                       *allSolutions,
+		Parallelism:                        *parallelism,
+		SolutionFile:                       *solutionFile,
+		Configuration:                      *configuration,
+		BuildVersion:                       *buildVersion,
+		Project:                            *project,
+		RuntimeIDs:                         parseList(*runtimeID),
+		ForceRestore:                       *forceRestore,
+		ForceBuild:                         *forceBuild,
+		OutputFolder:                       *outputFolder,
+		PackagesFolder:                     *packagesFolder,
+		NugetSources:                       *nugetSources,
+		NugetServerURL:                     *nugetServerURL,
+		NugetServerAPIKey:                  *nugetServerAPIKey,
+		NugetServerCredentialsJSONPath:     *nugetServerCredentialsJSONPath,
+		NugetServerName:                    *nugetServerName,
+		NugetServerNames:                   parseList(*nugetServerNames),
+		NugetServerUsername:                *nugetServerUsername,
+		NugetSourceName:                    *nugetSourceName,
+		NugetPushServerNames:               parseList(*nugetPushServerNames),
+		NugetPushPolicy:                    *nugetPushPolicy,
+		NugetPackageRules:                  parseList(*nugetPackageRules),
+		NugetPackageInclude:                parseList(*nugetPackageInclude),
+		NugetPackageExclude:                parseList(*nugetPackageExclude),
+		NugetSymbolServerName:              *nugetSymbolServerName,
+		NugetExistingVersionPolicy:         *nugetExistingVersionPolicy,
+		NugetSkipDuplicate:                 *nugetSkipDuplicate,
+		PackageSourceMapping:               *packageSourceMapping,
+		LockedMode:                         *lockedMode,
+		PackageCacheDirectory:              *packageCacheDirectory,
+		Offline:                            *offline,
+		MaxRetries:                         *maxRetries,
+		RetryDelay:                         *retryDelay,
+		OfflineSource:                      *offlineSource,
+		IncludeSymbols:                     *includeSymbols,
+		SymbolPackageFormat:                *symbolPackageFormat,
+		PublishReadyToRun:                  *publishReadyToRun,
+		PublishSingleFile:                  *publishSingleFile,
+		PublishTrimmed:                     *publishTrimmed,
+		SonarQubeServerURL:                 *sonarQubeServerURL,
+		SonarQubeToken:                     *sonarQubeToken,
+		SonarQubeServerCredentialsJSONPath: *sonarQubeServerCredentialsJSONPath,
+		SonarQubeServerName:                *sonarQubeServerName,
+		SonarQubeCoverageExclusions:        *sonarQubeCoverageExclusions,
+		RequireTests:                       *requireTests,
+		TestTypeProperty:                   *testTypeProperty,
+		IntegrationTestCategory:            *integrationTestCategory,
+		DryRun:                             *dryRun,
+		Steps:                              parseList(*steps),
+		ReportPath:                         *reportPath,
+	}
+
+	// an empty label can't be told apart from an unset one, which gets the default path, so the report is skipped with a value of its own
+	if strings.EqualFold(cfg.ReportPath, noReportPath) {
+		cfg.ReportPath = ""
+	}
+
+	// the credential files are mounted on the C: drive for windows containers
+	if runtime.GOOS == "windows" {
+		cfg.NugetServerCredentialsJSONPath = "C:" + cfg.NugetServerCredentialsJSONPath
+		cfg.SonarQubeServerCredentialsJSONPath = "C:" + cfg.SonarQubeServerCredentialsJSONPath
+	}
+
+	return cfg
+}
+
+// Parses a list label, which is either passed as a json array or as a comma-separated string.
+func parseList(value string) []string {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil
+	}
+
+	var items []string
+	if strings.HasPrefix(value, "[") && json.Unmarshal([]byte(value), &items) == nil {
+		return items
+	}
+
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
+
+// Parses a mapping label, which is passed as a json object with a list or a comma-separated string per key.
+func parseMapping(value string) (map[string][]string, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, nil
+	}
+
+	var raw map[string]json.RawMessage
+	err := json.Unmarshal([]byte(value), &raw)
+	if err != nil {
+		return nil, fmt.Errorf("failed parsing %v as a json object: %w", value, err)
+	}
+
+	mapping := map[string][]string{}
+	for key, rawItems := range raw {
+		var items []string
+		if json.Unmarshal(rawItems, &items) != nil {
+			var item string
+			if json.Unmarshal(rawItems, &item) != nil {
+				return nil, fmt.Errorf("the value of %v is neither a list nor a string", key)
+			}
+			items = parseList(item)
+		}
+		mapping[key] = items
+	}
+
+	return mapping, nil
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseList(t *testing.T) {
+	for input, expected := range map[string][]string{
+		"":                                nil,
+		"restore, build,unit-test":        {"restore", "build", "unit-test"},
+		`["restore","build","unit-test"]`: {"restore", "build", "unit-test"},
+	} {
+		actual := parseList(input)
+		if strings.Join(actual, "|") != strings.Join(expected, "|") {
+			t.Errorf("parseList(%q) returned %q, expected %q", input, actual, expected)
+		}
+	}
+}
+
+func TestParseMapping(t *testing.T) {
+	mapping, err := parseMapping(`{"nuget.org": ["*"], "internal-nuget": "Acme.*, Contoso.*"}`)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mapping) != 2 || strings.Join(mapping["nuget.org"], "|") != "*" || strings.Join(mapping["internal-nuget"], "|") != "Acme.*|Contoso.*" {
+		t.Errorf("unexpected mapping %q", mapping)
+	}
+
+	_, err = parseMapping("nuget.org=*")
+	if err == nil {
+		t.Errorf("expected an error for a value which isn't a json object")
+	}
+}
+
+func TestNewConfigFromFlagsSkipsReportForNone(t *testing.T) {
+	defer func(value string) { *reportPath = value }(*reportPath)
+
+	for value, expected := range map[string]string{
+		"none":                          "",
+		"None":                          "",
+		".estafette/dotnet-report.json": ".estafette/dotnet-report.json",
+	} {
+		*reportPath = value
+
+		cfg := newConfigFromFlags(t.TempDir())
+
+		if cfg.ReportPath != expected {
+			t.Errorf("expected report path %q for %q, got %q", expected, value, cfg.ReportPath)
+		}
+	}
+}
@@ -1,6 +1,13 @@
 package main
 
-import "log"
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+
+	foundation "github.com/estafette/estafette-foundation"
+)
 
 // NugetServerCredentials are credentials defined in the CI server and injected into this trusted image
 type NugetServerCredentials struct {
@@ -13,8 +20,15 @@ type NugetServerCredentials struct {
 type NugetServerCredentialsAdditionalProperties struct {
 	APIURL string `json:"apiUrl,omitempty"`
 	APIKey string `json:"apiKey,omitempty"`
+	// Username is the account the key belongs to, for feeds like GitHub Packages that check it; it defaults to the source name
+	Username string `json:"username,omitempty"`
+	// SourceName is the name of the package source registered for restoring; it defaults to the name of the credential
+	SourceName string `json:"sourceName,omitempty"`
 }
 
+// the source name of NuGet credentials set with the nugetServerUrl and nugetServerApiKey labels instead of the credentials file
+const defaultNugetSourceName = "nuget-server"
+
 // SonarQubeServerCredentials are credentials defined in the CI server and injected into this trusted image
 type SonarQubeServerCredentials struct {
 	Name                 string                                         `json:"name,omitempty"`
@@ -59,3 +73,310 @@ func GetSonarQubeServerCredentialsByName(c []SonarQubeServerCredentials, name st
 	log.Printf("Credential with name %v was not found.", name)
 	return nil
 }
+
+// Returns the credential with the specified name from the credentials file, or the first credential if no name is specified.
+func readNugetServerCredentialsFile(credentialsFilePath string, serverName string) (*NugetServerCredentials, error) {
+	credentials, err := readAllNugetServerCredentials(credentialsFilePath)
+	if err != nil {
+		return nil, err
+	}
+
+	// Just pick the first
+	credential := &credentials[0]
+	if serverName != "" {
+		credential = GetNugetServerCredentialsByName(credentials, serverName)
+		if credential == nil {
+			return nil, fmt.Errorf("the NuGet Server credential with the name %v does not exist", serverName)
+		}
+	}
+
+	return credential, nil
+}
+
+// Returns all credentials of the credentials file, and fails if there are none.
+func readAllNugetServerCredentials(credentialsFilePath string) ([]NugetServerCredentials, error) {
+	log.Printf("Reading credentials from file at path %v...", credentialsFilePath)
+	credentialsFileContent, err := os.ReadFile(credentialsFilePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed reading credential file at path %v: %w", credentialsFilePath, err)
+	}
+
+	var credentials []NugetServerCredentials
+	err = json.Unmarshal(credentialsFileContent, &credentials)
+	if err != nil {
+		return nil, fmt.Errorf("failed unmarshalling credentials: %w", err)
+	}
+
+	if len(credentials) == 0 {
+		return nil, fmt.Errorf("there are no credentials specified")
+	}
+
+	return credentials, nil
+}
+
+// Returns the name of the package source for the credential, which is the sourceName property or else the name of the credential.
+func (c *NugetServerCredentials) getSourceName() string {
+	if c.AdditionalProperties.SourceName != "" {
+		return c.AdditionalProperties.SourceName
+	}
+
+	return c.Name
+}
+
+// Returns the username for the credential, which is the username property or else the source name, for feeds which only check the key.
+func (c *NugetServerCredentials) getUsername() string {
+	if c.AdditionalProperties.Username != "" {
+		return c.AdditionalProperties.Username
+	}
+
+	return c.getSourceName()
+}
+
+// GetSonarQubeServerCredentialsFromFile reads the credentials file and returns the url and token of the credential with the specified name, or of the first credential if no name is specified
+func GetSonarQubeServerCredentialsFromFile(credentialsFilePath string, serverName string) (serverURL string, token string, err error) {
+	log.Printf("Reading credentials from file at path %v...", credentialsFilePath)
+	credentialsFileContent, err := os.ReadFile(credentialsFilePath)
+	if err != nil {
+		return "", "", fmt.Errorf("failed reading credential file at path %v: %w", credentialsFilePath, err)
+	}
+
+	var credentials []SonarQubeServerCredentials
+	err = json.Unmarshal(credentialsFileContent, &credentials)
+	if err != nil {
+		return "", "", fmt.Errorf("failed unmarshalling credentials: %w", err)
+	}
+
+	if len(credentials) == 0 {
+		return "", "", fmt.Errorf("there are no credentials specified")
+	}
+
+	// Just pick the first
+	credential := &credentials[0]
+	if serverName != "" {
+		credential = GetSonarQubeServerCredentialsByName(credentials, serverName)
+		if credential == nil {
+			return "", "", fmt.Errorf("the SonarQube Server credential with the name %v does not exist", serverName)
+		}
+	}
+
+	return credential.AdditionalProperties.APIURL, credential.AdditionalProperties.Token, nil
+}
+
+// Returns the NuGet server credential from the labels, or else from the mounted credentials file if it exists, or nil if there are neither.
+// The nugetServerUsername and nugetSourceName labels override the properties of the credential.
+func resolveNugetServerCredentials(cfg Config, serverName string) (*NugetServerCredentials, error) {
+	var credential *NugetServerCredentials
+	switch {
+	case cfg.NugetServerURL != "" && cfg.NugetServerAPIKey != "":
+		credential = &NugetServerCredentials{
+			Name: defaultNugetSourceName,
+			AdditionalProperties: NugetServerCredentialsAdditionalProperties{
+				APIURL: cfg.NugetServerURL,
+				APIKey: cfg.NugetServerAPIKey,
+			},
+		}
+	// use mounted credential file if present instead of relying on an envvar
+	case foundation.FileExists(cfg.NugetServerCredentialsJSONPath):
+		var err error
+		credential, err = readNugetServerCredentialsFile(cfg.NugetServerCredentialsJSONPath, serverName)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, nil
+	}
+
+	if cfg.NugetServerUsername != "" {
+		credential.AdditionalProperties.Username = cfg.NugetServerUsername
+	}
+	if cfg.NugetSourceName != "" {
+		credential.AdditionalProperties.SourceName = cfg.NugetSourceName
+	}
+
+	return credential, nil
+}
+
+// Returns the credentials of the NuGet servers to restore from, which are the ones selected with nugetServerNames, or else the single one of resolveNugetServerCredentials.
+// Credentials without url or key are left out, as they can't be used to authenticate.
+func resolveRestoreNugetServerCredentials(cfg Config) ([]*NugetServerCredentials, error) {
+	var credentials []*NugetServerCredentials
+
+	if len(cfg.NugetServerNames) == 0 {
+		credential, err := resolveNugetServerCredentials(cfg, cfg.NugetServerName)
+		if err != nil {
+			return nil, err
+		}
+		if credential != nil {
+			credentials = append(credentials, credential)
+		}
+	} else {
+		// the server set with the labels comes first, like it wins over the credentials file for a single server
+		if cfg.NugetServerURL != "" && cfg.NugetServerAPIKey != "" {
+			credential, err := resolveNugetServerCredentials(cfg, "")
+			if err != nil {
+				return nil, err
+			}
+			credentials = append(credentials, credential)
+		}
+
+		all, err := readAllNugetServerCredentials(cfg.NugetServerCredentialsJSONPath)
+		if err != nil {
+			return nil, err
+		}
+		if len(cfg.NugetServerNames) == 1 && cfg.NugetServerNames[0] == "all" {
+			for i := range all {
+				credentials = append(credentials, &all[i])
+			}
+		} else {
+			for _, name := range cfg.NugetServerNames {
+				credential := GetNugetServerCredentialsByName(all, name)
+				if credential == nil {
+					return nil, fmt.Errorf("the NuGet Server credential with the name %v listed in nugetServerNames does not exist", name)
+				}
+				credentials = append(credentials, credential)
+			}
+		}
+	}
+
+	usable := []*NugetServerCredentials{}
+	for _, credential := range credentials {
+		if credential.AdditionalProperties.APIURL == "" || credential.AdditionalProperties.APIKey == "" {
+			log.Printf("Skipping NuGet Server credential %v without url or key.", credential.Name)
+			continue
+		}
+		usable = append(usable, credential)
+	}
+
+	return usable, nil
+}
+
+// nugetSourceCredential is a NuGet server credential with the name of the package source it authenticates
+type nugetSourceCredential struct {
+	sourceName string
+	credential *NugetServerCredentials
+	// existing is the source of the NuGet config with the same name or url, which is authenticated instead of adding another source
+	existing *NugetSource
+}
+
+// Returns the package sources the credentials authenticate, named after the credential, or after the source of the NuGet config with the same name or url.
+// Credentials with the same source name get a numbered suffix, in the order they're selected.
+func getNugetSourceCredentials(credentials []*NugetServerCredentials, nugetConfig *NugetConfig) []nugetSourceCredential {
+	var sources []nugetSourceCredential
+	var names []string
+	for _, credential := range credentials {
+		sourceName := credential.getSourceName()
+		for i := 2; foundation.StringArrayContains(names, sourceName); i++ {
+			sourceName = fmt.Sprintf("%v-%v", credential.getSourceName(), i)
+		}
+
+		source := nugetSourceCredential{sourceName: sourceName, credential: credential}
+		if nugetConfig != nil {
+			if existing := nugetConfig.findSource(sourceName, credential.AdditionalProperties.APIURL); existing != nil {
+				source.sourceName = existing.Name
+				source.existing = existing
+			}
+		}
+
+		names = append(names, source.sourceName)
+		sources = append(sources, source)
+	}
+
+	return sources
+}
+
+// Returns the environment variables which pass the credentials to NuGet for the sources, and the secrets to mask in them.
+// NuGet reads NuGetPackageSourceCredentials_<source name> itself and the credential provider reads VSS_NUGET_EXTERNAL_FEED_ENDPOINTS,
+// so the keys are never written to a config file or passed as arguments, where they'd be visible in the process list.
+func getNugetCredentialsEnv(sources []nugetSourceCredential) (env []string, secrets []string) {
+	if len(sources) == 0 {
+		return nil, nil
+	}
+
+	type endpointCredential struct {
+		Endpoint string `json:"endpoint"`
+		Username string `json:"username"`
+		Password string `json:"password"`
+	}
+	var endpoints struct {
+		EndpointCredentials []endpointCredential `json:"endpointCredentials"`
+	}
+
+	for _, s := range sources {
+		username, apiKey := s.credential.getUsername(), s.credential.AdditionalProperties.APIKey
+		env = append(env, fmt.Sprintf("NuGetPackageSourceCredentials_%v=Username=%v;Password=%v", s.sourceName, username, apiKey))
+		endpoints.EndpointCredentials = append(endpoints.EndpointCredentials, endpointCredential{
+			Endpoint: s.credential.AdditionalProperties.APIURL,
+			Username: username,
+			Password: apiKey,
+		})
+		secrets = append(secrets, apiKey)
+	}
+
+	// marshalling a struct of strings can't fail
+	endpointsJSON, _ := json.Marshal(endpoints)
+	env = append(env, "VSS_NUGET_EXTERNAL_FEED_ENDPOINTS="+string(endpointsJSON))
+
+	return env, secrets
+}
+
+// the credential push-nuget uses when neither the labels nor nugetServerName name a server
+const defaultPushNugetServerName = "github-nuget"
+
+// Returns the credentials of the NuGet servers to push to, which are exactly the ones listed in nugetPushServerNames,
+// or else the single server of the labels or of the credential named by nugetServerName, github-nuget by default.
+// Every server needs a url and key, as a push can't be anonymous.
+func resolvePushNugetServerCredentials(cfg Config) ([]*NugetServerCredentials, error) {
+	var credentials []*NugetServerCredentials
+
+	if len(cfg.NugetPushServerNames) == 0 {
+		serverName := cfg.NugetServerName
+		if serverName == "" {
+			serverName = defaultPushNugetServerName
+		}
+		credential, err := resolveNugetServerCredentials(cfg, serverName)
+		if err != nil {
+			return nil, err
+		}
+		if credential == nil {
+			return nil, fmt.Errorf("the NuGet server URL and API key have to be specified to push a package")
+		}
+		credentials = append(credentials, credential)
+	} else {
+		all, err := readAllNugetServerCredentials(cfg.NugetServerCredentialsJSONPath)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range cfg.NugetPushServerNames {
+			credential := GetNugetServerCredentialsByName(all, name)
+			if credential == nil {
+				return nil, fmt.Errorf("the NuGet Server credential with the name %v listed in nugetPushServerNames does not exist", name)
+			}
+			credentials = append(credentials, credential)
+		}
+	}
+
+	for _, credential := range credentials {
+		if credential.AdditionalProperties.APIURL == "" || credential.AdditionalProperties.APIKey == "" {
+			return nil, fmt.Errorf("the NuGet server URL and API key have to be specified to push a package, but credential %v doesn't have both", credential.Name)
+		}
+	}
+
+	return credentials, nil
+}
+
+// Returns the credential of the symbol server named by nugetSymbolServerName, or nil if it isn't set.
+func resolveSymbolNugetServerCredentials(cfg Config) (*NugetServerCredentials, error) {
+	if cfg.NugetSymbolServerName == "" {
+		return nil, nil
+	}
+
+	credential, err := readNugetServerCredentialsFile(cfg.NugetServerCredentialsJSONPath, cfg.NugetSymbolServerName)
+	if err != nil {
+		return nil, err
+	}
+	if credential.AdditionalProperties.APIURL == "" || credential.AdditionalProperties.APIKey == "" {
+		return nil, fmt.Errorf("the NuGet server URL and API key have to be specified to push the symbol packages, but credential %v doesn't have both", credential.Name)
+	}
+
+	return credential, nil
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Returns a readable plan of the commands an action would execute, with all secrets masked.
+func formatPlan(cfg Config, commands []Command) string {
+	var sb strings.Builder
+
+	solutionName := cfg.SolutionName
+	if solutionName == "" {
+		solutionName = "<unknown>"
+	}
+	buildVersion := cfg.BuildVersion
+	if buildVersion == "" {
+		buildVersion = "<none>"
+	}
+
+	fmt.Fprintf(&sb, "Dry run of action %v\n", cfg.Action)
+	fmt.Fprintf(&sb, "  solution: %v\n", solutionName)
+	fmt.Fprintf(&sb, "  version: %v\n", buildVersion)
+	fmt.Fprintf(&sb, "  working directory: %v\n", cfg.WorkingDirectory)
+
+	if len(commands) == 0 {
+		sb.WriteString("No commands would be executed.")
+		return sb.String()
+	}
+
+	sb.WriteString("The following commands would be executed:")
+	for i, c := range commands {
+		fmt.Fprintf(&sb, "\n  %d. %v", i+1, c)
+		if c.Dir != "" && c.Dir != cfg.WorkingDirectory {
+			fmt.Fprintf(&sb, " (in %v)", c.Dir)
+		}
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+func TestFormatPlan(t *testing.T) {
+	cfg := newTestConfig(t, "webservice")
+	cfg.Action = "restore"
+	cfg.BuildVersion = "1.2.3"
+	cfg.NugetServerCredentialsJSONPath, _ = filepath.Abs("testdata/credentials/nuget_server.json")
+	runner := &FakeCommandRunner{}
+	err := runAction(context.Background(), runner, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plan := formatPlan(cfg, runner.Commands)
+	configPath := runner.Commands[0].Args[len(runner.Commands[0].Args)-1]
+
+	expected := "Dry run of action restore\n" +
+		"  solution: Acme.FooApi\n" +
+		"  version: 1.2.3\n" +
+		"  working directory: " + cfg.WorkingDirectory + "\n" +
+		"The following commands would be executed:\n" +
+		"  1. dotnet nuget add source --name github-nuget https://nuget.pkg.github.com/acme/index.json --configfile " + configPath + "\n" +
+		"  2. NuGetPackageSourceCredentials_github-nuget=******** VSS_NUGET_EXTERNAL_FEED_ENDPOINTS=******** dotnet restore Acme.FooApi.sln --packages .nuget/packages --configfile " + configPath
+	if plan != expected {
+		t.Errorf("unexpected plan\nexpected:\n%v\nactual:\n%v", expected, plan)
+	}
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// LockFile is a packages.lock.json file, in which NuGet records the exact packages a project restores
+type LockFile struct {
+	Version int `json:"version"`
+	// Dependencies holds the locked packages per target framework
+	Dependencies map[string]map[string]LockedPackage `json:"dependencies"`
+}
+
+// LockedPackage is a package in a lock file
+type LockedPackage struct {
+	// Type is Direct for a package referenced by the project, and Transitive or Project otherwise
+	Type         string            `json:"type"`
+	Requested    string            `json:"requested,omitempty"`
+	Resolved     string            `json:"resolved,omitempty"`
+	ContentHash  string            `json:"contentHash,omitempty"`
+	Dependencies map[string]string `json:"dependencies,omitempty"`
+}
+
+// lockedProject is a project of the solution which restores with a lock file
+type lockedProject struct {
+	// path is relative to the working directory
+	path         string
+	projectFile  *ProjectFile
+	lockFilePath string
+}
+
+// ReadLockFile reads a packages.lock.json file
+func ReadLockFile(lockFilePath string) (*LockFile, error) {
+	content, err := os.ReadFile(lockFilePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed reading lock file %v: %w", lockFilePath, err)
+	}
+
+	var lockFile LockFile
+	err = json.Unmarshal(content, &lockFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed parsing lock file %v: %w", lockFilePath, err)
+	}
+
+	return &lockFile, nil
+}
+
+// Returns the differences between the package references of the project and the direct dependencies in the lock file, which make a restore in locked mode fail.
+// Package references without a version, like with central package management, are only checked for being in the lock file.
+func (l *LockFile) getDivergences(projectFile *ProjectFile) []string {
+	locked := map[string]LockedPackage{}
+	names := map[string]string{}
+	for _, packages := range l.Dependencies {
+		for name, p := range packages {
+			if p.Type == "Direct" {
+				locked[strings.ToLower(name)] = p
+				names[strings.ToLower(name)] = name
+			}
+		}
+	}
+
+	var divergences []string
+	referenced := map[string]bool{}
+	for _, reference := range projectFile.PackageReferences {
+		key := strings.ToLower(reference.Include)
+		referenced[key] = true
+
+		p, ok := locked[key]
+		switch {
+		case !ok:
+			divergences = append(divergences, fmt.Sprintf("%v %v is referenced but not in the lock file", reference.Include, reference.Version))
+		case reference.Version != "" && !strings.Contains(reference.Version, "$(") && normalizeVersionRange(reference.Version) != normalizeVersionRange(p.Requested):
+			divergences = append(divergences, fmt.Sprintf("%v is referenced as %v but locked as %v", reference.Include, reference.Version, p.Requested))
+		}
+	}
+
+	var removed []string
+	for key := range locked {
+		if !referenced[key] {
+			removed = append(removed, fmt.Sprintf("%v %v is in the lock file but no longer referenced", names[key], locked[key].Resolved))
+		}
+	}
+	sort.Strings(removed)
+
+	return append(divergences, removed...)
+}
+
+// Returns the version range NuGet records for a requested version, where a plain version is a minimum version.
+func normalizeVersionRange(version string) string {
+	version = strings.ReplaceAll(version, " ", "")
+	if version != "" && !strings.HasPrefix(version, "[") && !strings.HasPrefix(version, "(") {
+		return "[" + version + ",)"
+	}
+
+	return version
+}
+
+// Returns the projects of the solution which enable RestorePackagesWithLockFile, in the project itself or in a Directory.Build.props file.
+func getLockedProjects(solution *Solution, workingDir string) ([]lockedProject, error) {
+	var projects []lockedProject
+	for _, p := range solution.getProjects() {
+		projectFile, err := solution.readProjectFile(p)
+		if err != nil {
+			return nil, err
+		}
+		if projectFile == nil {
+			continue
+		}
+
+		projectPath := filepath.Join(solution.Dir, filepath.FromSlash(p.Path))
+		enabled, err := getProjectProperty(projectPath, projectFile, "RestorePackagesWithLockFile")
+		if err != nil {
+			return nil, err
+		}
+		if !strings.EqualFold(enabled, "true") {
+			continue
+		}
+
+		lockFileName, err := getProjectProperty(projectPath, projectFile, "NuGetLockFilePath")
+		if err != nil {
+			return nil, err
+		}
+		if lockFileName == "" {
+			lockFileName = "packages.lock.json"
+		}
+
+		projects = append(projects, lockedProject{
+			path:         solution.getProjectPath(p, workingDir),
+			projectFile:  projectFile,
+			lockFilePath: filepath.Join(filepath.Dir(projectPath), filepath.FromSlash(strings.ReplaceAll(lockFileName, `\`, "/"))),
+		})
+	}
+
+	return projects, nil
+}
+
+// Returns the property of the project, or else of the nearest Directory.Build.props file above it, which msbuild imports automatically.
+func getProjectProperty(projectPath string, projectFile *ProjectFile, name string) (string, error) {
+	if value, ok := projectFile.Properties[name]; ok {
+		return value, nil
+	}
+
+	for dir := filepath.Dir(projectPath); ; dir = filepath.Dir(dir) {
+		propsPath := filepath.Join(dir, "Directory.Build.props")
+		if _, err := os.Stat(propsPath); err == nil {
+			props, err := ReadProjectFile(propsPath)
+			if err != nil {
+				return "", err
+			}
+			return props.Properties[name], nil
+		}
+		if filepath.Dir(dir) == dir {
+			return "", nil
+		}
+	}
+}
+
+var lockFileErrorRegex = regexp.MustCompile(`(?m)^.*error NU1004:.*$`)
+
+// Returns an error listing the projects and packages which diverged from their lock file, for a restore in locked mode which failed.
+// If the divergences can't be determined from the files, the NU1004 errors dotnet printed are listed instead.
+func describeLockFileDivergences(projects []lockedProject, output string, err error) error {
+	var sb strings.Builder
+	for _, p := range projects {
+		lockFile, readErr := ReadLockFile(p.lockFilePath)
+		if readErr != nil {
+			continue
+		}
+		for _, divergence := range lockFile.getDivergences(p.projectFile) {
+			fmt.Fprintf(&sb, "\n  %v: %v", p.path, divergence)
+		}
+	}
+
+	if sb.Len() == 0 {
+		for _, line := range dedupe(lockFileErrorRegex.FindAllString(output, -1)) {
+			fmt.Fprintf(&sb, "\n  %v", strings.TrimSpace(line))
+		}
+	}
+
+	if sb.Len() == 0 {
+		return fmt.Errorf("restoring in locked mode failed: %w", err)
+	}
+
+	return fmt.Errorf("restoring in locked mode failed, because packages diverged from the lock files; restore without lockedMode and commit the updated lock files:%v\n%w", sb.String(), err)
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestGetLockedProjects(t *testing.T) {
+	workingDir, _ := filepath.Abs("testdata/locked")
+	solution, err := findSolution(workingDir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	projects, err := getLockedProjects(solution, workingDir)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	var actual []string
+	for _, p := range projects {
+		actual = append(actual, p.path+" "+relativePath(workingDir, p.lockFilePath))
+	}
+	expected := []string{
+		"src/Acme.Locked/Acme.Locked.csproj src/Acme.Locked/packages.lock.json",
+		"src/Acme.Locked.Contracts/Acme.Locked.Contracts.csproj src/Acme.Locked.Contracts/packages.lock.json",
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("unexpected locked projects %q", actual)
+	}
+}
+
+func TestLockFileDivergences(t *testing.T) {
+	lockFile, err := ReadLockFile("testdata/locked/src/Acme.Locked/packages.lock.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	projectFile, err := ReadProjectFile("testdata/locked/src/Acme.Locked/Acme.Locked.csproj")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	divergences := lockFile.getDivergences(projectFile)
+
+	expected := []string{
+		"Newtonsoft.Json is referenced as 13.0.3 but locked as [13.0.1, )",
+		"Serilog 3.1.1 is referenced but not in the lock file",
+		"Polly 8.2.0 is in the lock file but no longer referenced",
+	}
+	if !reflect.DeepEqual(divergences, expected) {
+		t.Errorf("unexpected divergences %q", divergences)
+	}
+}
+
+func TestNormalizeVersionRange(t *testing.T) {
+	for input, expected := range map[string]string{
+		"13.0.1":     "[13.0.1,)",
+		"[13.0.1, )": "[13.0.1,)",
+		"[1.0, 2.0)": "[1.0,2.0)",
+		"[13.0.1]":   "[13.0.1]",
+		"":           "",
+	} {
+		if actual := normalizeVersionRange(input); actual != expected {
+			t.Errorf("normalizeVersionRange(%q) returned %q, expected %q", input, actual, expected)
+		}
+	}
+}
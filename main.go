@@ -2,8 +2,6 @@ package main
 
 import (
 	"context"
-	"encoding/json"
-	"fmt"
 	"os"
 	"path/filepath"
 	"runtime"
@@ -26,11 +24,11 @@ var (
 
 var (
 	// flags
-	action                             = kingpin.Flag("action", "Any of the following actions: restore, build, test, unit-test, integration-test, publish, pack, push-nuget").Envar("ESTAFETTE_EXTENSION_ACTION").String()
+	action                             = kingpin.Flag("action", "Any of the following actions: "+strings.Join(getActionNames(), ", ")+".").Envar("ESTAFETTE_EXTENSION_ACTION").String()
 	configuration                      = kingpin.Flag("configuration", "The build configuration.").Envar("ESTAFETTE_EXTENSION_CONFIGURATION").Default("Release").String()
 	buildVersion                       = kingpin.Flag("buildVersion", "The build version.").Envar("ESTAFETTE_EXTENSION_BUILD_VERSION").String()
 	project                            = kingpin.Flag("project", "The path to the project for which the tests/build should be run.").Envar("ESTAFETTE_EXTENSION_PROJECT").String()
-	runtimeID                          = kingpin.Flag("runtimeId", "The publish runtime.").Envar("ESTAFETTE_EXTENSION_RUNTIME_ID").Default("linux-x64").String()
+	runtimeID                          = kingpin.Flag("runtimeId", "The runtime identifier to publish for, or the list of runtime identifiers to restore for.").Envar("ESTAFETTE_EXTENSION_RUNTIME_ID").String()
 	forceRestore                       = kingpin.Flag("forceRestore", "Execute the restore on every action.").Envar("ESTAFETTE_EXTENSION_FORCE_RESTORE").Default("false").Bool()
 	forceBuild                         = kingpin.Flag("forceBuild", "Execute the build on every action.").Envar("ESTAFETTE_EXTENSION_FORCE_BUILD").Default("false").Bool()
 	outputFolder                       = kingpin.Flag("outputFolder", "The folder into which the publish output is generated.").Envar("ESTAFETTE_EXTENSION_OUTPUT_FOLDER").String()
@@ -40,7 +38,24 @@ var (
 	nugetServerAPIKey                  = kingpin.Flag("nugetServerApiKey", "The API key of the NuGet server.").Envar("ESTAFETTE_EXTENSION_NUGET_SERVER_API_KEY").String()
 	nugetServerCredentialsJSONPath     = kingpin.Flag("nugetServerCredentials-path", "Path to file with NuGet Server credentials configured at server level, passed in to this trusted extension.").Default("/credentials/nuget_server.json").String()
 	nugetServerName                    = kingpin.Flag("nugetServerName", "The name of the preferred NuGet server from the preconfigured credentials.").Envar("ESTAFETTE_EXTENSION_NUGET_SERVER_NAME").Default("github-nuget").String()
+	nugetServerNames                   = kingpin.Flag("nugetServerNames", "The names of the preconfigured NuGet server credentials to add as restore sources, or all to add all of them.").Envar("ESTAFETTE_EXTENSION_NUGET_SERVER_NAMES").String()
+	nugetServerUsername                = kingpin.Flag("nugetServerUsername", "The username for the NuGet server, overriding the username of the preconfigured credential.").Envar("ESTAFETTE_EXTENSION_NUGET_SERVER_USERNAME").String()
+	nugetPushServerNames               = kingpin.Flag("nugetPushServerNames", "The names of the credentials of the NuGet servers to push to, as a json array or comma-separated string.").Envar("ESTAFETTE_EXTENSION_NUGET_PUSH_SERVER_NAMES").String()
+	nugetPushPolicy                    = kingpin.Flag("nugetPushPolicy", "What to do when a push to one of several servers fails: fail-all stops and fails the action, best-effort pushes to the other servers and only fails when all of them failed.").Envar("ESTAFETTE_EXTENSION_NUGET_PUSH_POLICY").Default("fail-all").String()
+	nugetPackageRules                  = kingpin.Flag("nugetPackageRules", "The rules the packages have to pass before they're pushed, as a json array or comma-separated string of fileName, buildVersion, authors, license, repository, readme and testAssemblies, or all or none.").Envar("ESTAFETTE_EXTENSION_NUGET_PACKAGE_RULES").Default("fileName,buildVersion,testAssemblies").String()
+	nugetPackageInclude                = kingpin.Flag("nugetPackageInclude", "Glob patterns of the ids of the packages to push, as a json array or comma-separated string.").Envar("ESTAFETTE_EXTENSION_NUGET_PACKAGE_INCLUDE").String()
+	nugetPackageExclude                = kingpin.Flag("nugetPackageExclude", "Glob patterns of the ids of the packages not to push, as a json array or comma-separated string.").Envar("ESTAFETTE_EXTENSION_NUGET_PACKAGE_EXCLUDE").String()
+	nugetSymbolServerName              = kingpin.Flag("nugetSymbolServerName", "The name of the preconfigured NuGet server credential to push the symbol packages to, instead of the server the packages are pushed to.").Envar("ESTAFETTE_EXTENSION_NUGET_SYMBOL_SERVER_NAME").String()
+	nugetSourceName                    = kingpin.Flag("nugetSourceName", "The name of the package source registered for the NuGet server, overriding the source name of the preconfigured credential.").Envar("ESTAFETTE_EXTENSION_NUGET_SOURCE_NAME").String()
+	nugetExistingVersionPolicy         = kingpin.Flag("nugetExistingVersionPolicy", "Checks the NuGet servers for the versions of the packages before pushing, and what to do with a version which already exists: fail, skip or warn.").Envar("ESTAFETTE_EXTENSION_NUGET_EXISTING_VERSION_POLICY").String()
 	nugetSkipDuplicate                 = kingpin.Flag("nugetSkipDuplicate", "Treat 409 Conflict response as a warning.").Envar("ESTAFETTE_EXTENSION_NUGET_SKIP_DUPLICATE").Default("false").Bool()
+	packageSourceMapping               = kingpin.Flag("packageSourceMapping", "The package ID patterns per restore source, as a json object, to set up NuGet package source mapping.").Envar("ESTAFETTE_EXTENSION_PACKAGE_SOURCE_MAPPING").String()
+	lockedMode                         = kingpin.Flag("lockedMode", "Restore in locked mode, failing when the packages diverge from the committed packages.lock.json files.").Envar("ESTAFETTE_EXTENSION_LOCKED_MODE").Default("false").Bool()
+	offline                            = kingpin.Flag("offline", "Restore only from the offlineSource folder and the package cache, without any remote sources.").Envar("ESTAFETTE_EXTENSION_OFFLINE").Default("false").Bool()
+	offlineSource                      = kingpin.Flag("offlineSource", "The local folder feed to restore from offline.").Envar("ESTAFETTE_EXTENSION_OFFLINE_SOURCE").String()
+	packageCacheDirectory              = kingpin.Flag("packageCacheDirectory", "The directory, like a mounted volume, in which the restored packages are cached between builds.").Envar("ESTAFETTE_EXTENSION_PACKAGE_CACHE_DIRECTORY").String()
+	includeSymbols                     = kingpin.Flag("includeSymbols", "Creates a symbol package next to every NuGet package in the pack action when true.").Envar("ESTAFETTE_EXTENSION_INCLUDE_SYMBOLS").Default("false").Bool()
+	symbolPackageFormat                = kingpin.Flag("symbolPackageFormat", "The format of the symbol packages, snupkg or symbols.nupkg.").Envar("ESTAFETTE_EXTENSION_SYMBOL_PACKAGE_FORMAT").Default("snupkg").String()
 	publishReadyToRun                  = kingpin.Flag("publishReadyToRun", "Sets PublishReadyToRun parameter for the publish action when true.").Envar("ESTAFETTE_EXTENSION_PUBLISH_READY_TO_RUN").Default("false").Bool()
 	publishSingleFile                  = kingpin.Flag("publishSingleFile", "Sets PublishSingleFile parameter for the publish action when true.").Envar("ESTAFETTE_EXTENSION_PUBLISH_SINGLE_FILE").Default("false").Bool()
 	publishTrimmed                     = kingpin.Flag("publishTrimmed", "Sets PublishTrimmed parameter for the publish action when true.").Envar("ESTAFETTE_EXTENSION_PUBLISH_TRIMMED").Default("false").Bool()
@@ -49,11 +64,24 @@ var (
 	sonarQubeServerCredentialsJSONPath = kingpin.Flag("sonarQubeServerCredentials-path", "Path to file with SonarQube Server credentials configured at server level, passed in to this trusted extension.").Default("/credentials/sonarqube_server.json").String()
 	sonarQubeServerName                = kingpin.Flag("sonarQubeServerName", "The name of the preferred SonarQube server from the preconfigured credentials.").Envar("ESTAFETTE_EXTENSION_SONARQUBE_SERVER_NAME").String()
 	sonarQubeCoverageExclusions        = kingpin.Flag("sonarQubeCoverageExclusions", "The path for the code to be excluded on SonarQube Scan.").Envar("ESTAFETTE_EXTENSION_SONARQUBE_COVERAGE_EXCLUSIONS").String()
+	workingDirectory                   = kingpin.Flag("workingDirectory", "The directory, relative to the repository root, in which the action runs.").Envar("ESTAFETTE_EXTENSION_WORKING_DIRECTORY").String()
+	allSolutions                       = kingpin.Flag("allSolutions", "Run the action for every solution found under the working directory.").Envar("ESTAFETTE_EXTENSION_ALL_SOLUTIONS").Default("false").Bool()
+	maxRetries                         = kingpin.Flag("maxRetries", "The number of times a restore or push is retried when it fails on a transient NuGet error.").Envar("ESTAFETTE_EXTENSION_MAX_RETRIES").Default("3").Int()
+	retryDelay                         = kingpin.Flag("retryDelay", "The delay before the first retry, which doubles for every next one.").Envar("ESTAFETTE_EXTENSION_RETRY_DELAY").Default("5s").Duration()
+	parallelism                        = kingpin.Flag("parallelism", "The number of solutions the action runs for in parallel when allSolutions is set.").Envar("ESTAFETTE_EXTENSION_PARALLELISM").Default("1").Int()
+	solutionFile                       = kingpin.Flag("solution", "The path or name of the solution, solution filter or .slnx file to use when the directory contains several.").Envar("ESTAFETTE_EXTENSION_SOLUTION").String()
+	requireTests                       = kingpin.Flag("requireTests", "Fail the test actions when no test projects or no tests are found.").Envar("ESTAFETTE_EXTENSION_REQUIRE_TESTS").Default("true").Bool()
+	testTypeProperty                   = kingpin.Flag("testTypeProperty", "The project property telling whether a test project contains unit or integration tests.").Envar("ESTAFETTE_EXTENSION_TEST_TYPE_PROPERTY").Default("TestType").String()
+	integrationTestCategory            = kingpin.Flag("integrationTestCategory", "The test category of integration tests, to tell them apart from unit tests in the same project.").Envar("ESTAFETTE_EXTENSION_INTEGRATION_TEST_CATEGORY").String()
+	steps                              = kingpin.Flag("steps", "The ordered list of actions executed by the ci action.").Envar("ESTAFETTE_EXTENSION_STEPS").String()
+	reportPath                         = kingpin.Flag("reportPath", "The path, relative to the working directory, of the json report of the action; a dotenv file with the .env extension is written next to it, or none to skip the report.").Envar("ESTAFETTE_EXTENSION_REPORT_PATH").Default(".estafette/dotnet-report.json").String()
+	dryRun                             = kingpin.Flag("dryRun", "Print the commands the action would execute without running them.").Envar("ESTAFETTE_EXTENSION_DRY_RUN").Default("false").Bool()
 )
 
 func main() {
 
 	// parse command line parameters
+	kingpin.CommandLine.Help = actionsHelp()
 	kingpin.Parse()
 
 	// init log format from envvar ESTAFETTE_LOG_FORMAT
@@ -67,542 +95,66 @@ func main() {
 		log.Fatal().Err(err).Msg("Couldn't determine current working directory.")
 	}
 
+	if *workingDirectory != "" {
+		workingDir = filepath.Join(workingDir, *workingDirectory)
+		if !foundation.DirExists(workingDir) {
+			log.Fatal().Msgf("The working directory %v doesn't exist.", workingDir)
+		}
+	}
+
 	// set defaults
 	builtInBuildVersion := os.Getenv("ESTAFETTE_BUILD_VERSION")
 	if *buildVersion == "" {
 		*buildVersion = builtInBuildVersion
 	}
 
-	solutionName, _ := getSolutionName()
-
-	if solutionName == "" {
-		log.Printf("Unknown solution")
-	} else {
-		log.Printf("Solution name: %s", solutionName)
-	}
-
-	switch *action {
-	case "restore": // Restore package dependencies with dotnet restore.
-
-		// Minimal example with defaults.
-		// image: extensions/dotnet:stable
-		// action: restore
-
-		// Determine the NuGet server credentials for restoring
-		// 1. If there is a NuGet.config file in the repository, we use that.
-		// 2. If nugetServerURL and nugetServerAPIKey are explicitly specified, we generate a NuGet.config file using those.
-		// 2. If we have the default credentials from the server level, and nugetServerName is explicitly specified, we look for the credential with the specified name.
-		// 3. If we have the default credentials from the server level, and nugetServerName is not specified, we take the first credential. (This is the sensible default if we're using only one NuGet server.)
-
-		configFileName := "nuget.config"
-		actualFileName := findActualNugetFileName(configFileName)
-		if foundation.FileExists(actualFileName) {
-			log.Fatal().Err(err).Msgf("The NuGet.config file was found in the repository and should be deleted. So then the common default sources are used. ")
-		}
-
-		if *nugetServerURL == "" || *nugetServerAPIKey == "" {
-			//nolint:errcheck
-			// use mounted credential file if present instead of relying on an envvar
-			if runtime.GOOS == "windows" {
-				*nugetServerCredentialsJSONPath = "C:" + *nugetServerCredentialsJSONPath
-			}
-
-			if foundation.FileExists(*nugetServerCredentialsJSONPath) {
-				*nugetServerURL, *nugetServerAPIKey = getNugetServerCredentialsFromFile(*nugetServerCredentialsJSONPath, *nugetServerName)
-			}
-		}
-
-		if *nugetServerURL != "" && *nugetServerAPIKey != "" {
-			log.Printf("Adding the NuGet source.\n")
-			log.Printf("> dotnet nuget add source --username travix-tooling-bot --password %v --store-password-in-clear-text --name travix %v\n", "********", *nugetServerURL)
-			foundation.RunCommandWithArgsWithoutLog(ctx, "dotnet", []string{"nuget", "add", "source", "--username", "travix-tooling-bot", "--password", *nugetServerAPIKey, "--store-password-in-clear-text", "--name", "travix", *nugetServerURL})
-		} else {
-			log.Printf("No custom NuGet credentials were found.\n")
-		}
-
-		// build docker image
-		log.Printf("Restoring packages...\n")
-		args := []string{
-			"restore",
-			"--packages",
-			".nuget/packages", // This is needed so the packages are restored into the working directory, so they're not lost between the stages.
-		}
-
-		if *nugetSources != "" {
-			nugetSourcesArray := strings.Split(*nugetSources, ",")
-
-			for _, source := range nugetSourcesArray {
-				args = append(args, "--source", source)
-			}
-		}
-
-		foundation.RunCommandWithArgs(ctx, "dotnet", args)
-
-	case "build": // Build the solution.
-
-		// Minimal example with defaults.
-		// image: extensions/dotnet:stable
-		// action: build
-
-		// Customizations.
-		// image: extensions/dotnet:stable
-		// action: build
-		// configuration: Debug
-		// versionSuffix: 5
-
-		log.Printf("Building the solution...\n")
-
-		args := []string{
-			"build",
-			"--configuration",
-			*configuration,
-			"/p:IncludeSourceRevisionInInformationalVersion=false",
-		}
-
-		if *buildVersion != "" {
-			args = append(args, fmt.Sprintf("/p:Version=%s", *buildVersion))
-		}
-
-		if !*forceRestore {
-			args = append(args, "--no-restore")
-		}
-
-		foundation.RunCommandWithArgs(ctx, "dotnet", args)
-
-	case "test": // Run the tests for the whole solution.
-
-		log.Printf("Running tests for every project in the ./test folder...\n")
-
-		runTests(ctx, "")
-
-	case "unit-test": // Run the unit tests.
-
-		log.Printf("Running tests for projects ending with UnitTests in the ./test folder...\n")
-
-		runTests(ctx, "UnitTests")
-
-	case "integration-test": // Run the integration tests.
-
-		log.Printf("Running tests for projects ending with IntegrationTests in the ./test folder...\n")
-
-		runTests(ctx, "IntegrationTests")
-
-	case "analyze-sonarqube": // Run the SonarQube analysis.
-
-		// Minimal example with defaults.
-		// image: extensions/dotnet:stable
-		// action: analyze-sonarqube
-
-		// Customizations.
-		// image: extensions/dotnet:stable
-		// action: analyze-sonarqube
-		// sonarQubeServerUrl: https://my-sonar-server.example.com
-		// sonarQubeCoverageExclusions: **Tests.cs
-
-		log.Printf("Running the SonarQube analysis...\n")
-
-		// Determine the SonarQube server credentials
-		// 1. If sonarQubeServerURL is explicitly specified, we use that.
-		// 2. If we have the default credentials from the server level, and sonarQubeServerName is explicitly specified, we look for the credential with the specified name.
-		// 3. If we have the default credentials from the server level, and sonarQubeServerName is not specified, we take the first credential. (This is the sensible default if we're using only one SonarQube server.)
-		if *sonarQubeServerURL == "" {
-			//nolint:errcheck
-			if runtime.GOOS == "windows" {
-				*sonarQubeServerCredentialsJSONPath = "C:" + *sonarQubeServerCredentialsJSONPath
-			}
-			if foundation.FileExists(*sonarQubeServerCredentialsJSONPath) {
-				log.Printf("Unmarshalling credentials...")
-
-				log.Info().Msgf("Reading credentials from file at path %v...", *sonarQubeServerCredentialsJSONPath)
-				credentialsFileContent, err := os.ReadFile(*sonarQubeServerCredentialsJSONPath)
-				if err != nil {
-					log.Fatal().Msgf("Failed reading credential file at path %v.", *sonarQubeServerCredentialsJSONPath)
-				}
-
-				var credentials []SonarQubeServerCredentials
-				err = json.Unmarshal(credentialsFileContent, &credentials)
-				if err != nil {
-					log.Fatal().Err(err).Msg("Failed unmarshalling credentials")
-				}
-
-				if len(credentials) == 0 {
-					log.Fatal().Msg("There were no credentials specified.")
-				}
-
-				if *sonarQubeServerName != "" {
-					credential := GetSonarQubeServerCredentialsByName(credentials, *sonarQubeServerName)
-					if credential == nil {
-						log.Fatal().Msgf("The NuGet Server credential with the name %v does not exist.", *sonarQubeServerName)
-					}
-
-					*sonarQubeServerURL = credential.AdditionalProperties.APIURL
-					*sonarQubeToken = credential.AdditionalProperties.Token
-				} else {
-					// Just pick the first
-					credential := credentials[0]
-
-					*sonarQubeServerURL = credential.AdditionalProperties.APIURL
-					*sonarQubeToken = credential.AdditionalProperties.Token
-				}
-			} else {
-				log.Fatal().Msg("The SonarQube server URL has to be specified to run the analysis.")
-			}
-		}
-		if *sonarQubeCoverageExclusions == "" {
-			*sonarQubeCoverageExclusions = "**Tests.cs"
-		}
-
-		// dotnet sonarscanner begin /k:"Travix.Core.ShoppingCart" /d:sonar.host.url=https://sonarqube.travix.com /d:sonar.cs.opencover.reportsPaths="**\coverage.opencover.xml" /d:sonar.coverage.exclusions="**Tests.cs"
-		args := []string{
-			"sonarscanner",
-			"begin",
-			fmt.Sprintf("/key:%s", solutionName),
-			fmt.Sprintf("/d:sonar.host.url=%s", *sonarQubeServerURL),
-			fmt.Sprintf("/d:sonar.login=%s", *sonarQubeToken),
-			"/d:sonar.cs.opencover.reportsPaths=\"**\\coverage.opencover.xml\"",
-			fmt.Sprintf("/d:sonar.coverage.exclusions=\"%s\"", *sonarQubeCoverageExclusions),
-		}
-
-		if *buildVersion != "" {
-			args = append(args, fmt.Sprintf("/version:%s", *buildVersion))
-		}
-
-		foundation.RunCommandWithArgs(ctx, "dotnet", args)
-
-		// dotnet build
-		args = []string{"build"}
-
-		if *buildVersion != "" {
-			args = append(args, fmt.Sprintf("/p:Version=%s", *buildVersion))
-		}
-
-		if !*forceRestore {
-			args = append(args, "--no-restore")
-		}
-
-		foundation.RunCommandWithArgs(ctx, "dotnet", args)
-
-		// Run unit tests with the extra arguments for coverage.
-		*forceBuild = true
-		runTests(ctx, "UnitTests", "/p:CollectCoverage=true", "/p:CoverletOutputFormat=opencover", "/p:CopyLocalLockFileAssemblies=true")
-
-		// dotnet sonarscanner end
-		args = []string{
-			"sonarscanner",
-			"end",
-			fmt.Sprintf("/d:sonar.login=%s", *sonarQubeToken),
-		}
-
-		foundation.RunCommandWithArgs(ctx, "dotnet", args)
-
-	case "publish": // Publish the final binaries.
-
-		// Minimal example with defaults.
-		// image: extensions/dotnet:stable
-		// action: publish
-
-		// Customizations.
-		// image: extensions/dotnet:stable
-		// action: publish
-		// project: src/CustomProject
-		// configuration: Debug
-		// runtimteId: windows10-x64
-		// outputFolder: ./binaries
-		// buildVersion: 1.5.0
-		// forceRestore: true
-
-		log.Printf("Publishing the binaries...\n")
-
-		// The solution is called Acme.FooApi, then we by default look for a project called Acme.FooApi.WebService, and if that doesn't exist, we fall back to simply Acme.FooApi
-		if *project == "" {
-			*project = fmt.Sprintf("src/%s.WebService", solutionName)
-			if _, err := os.Stat(*project); os.IsNotExist(err) {
-				*project = fmt.Sprintf("src/%s", solutionName)
-				if _, err := os.Stat(*project); os.IsNotExist(err) {
-					log.Fatal().Err(err).Msg("The project to be published can not be found. Please specify it with the 'project' label.")
-				}
-			}
-		}
-
-		if *outputFolder == "" {
-			// A default sensible choice is to put the publishing output directly under the working folder in a folder called "publish", so that its relative path doesn't depend on the project name.
-			// This makes it easier to use in a generic way in followup steps of the build.
-			*outputFolder = filepath.Join(workingDir, "publish")
-		}
-
-		args := []string{
-			"publish",
-			"--configuration",
-			*configuration,
-			"--runtime",
-			*runtimeID,
-			"--self-contained",
-			"true",
-			"--output",
-			*outputFolder,
-			*project,
-			"/p:IncludeSourceRevisionInInformationalVersion=false",
-		}
-
-		if *buildVersion != "" {
-			args = append(args, fmt.Sprintf("/p:Version=%s", *buildVersion))
-		}
+	cfg := newConfigFromFlags(workingDir)
 
-		if *publishReadyToRun {
-			args = append(args, "/p:PublishReadyToRun=true", "/p:PublishReadyToRunShowWarnings=true")
-		}
-		if *publishSingleFile {
-			args = append(args, "/p:PublishSingleFile=true")
-		}
-		if *publishTrimmed {
-			args = append(args, "/p:PublishTrimmed=true")
-		}
-
-		if !*forceRestore {
-			args = append(args, "--no-restore")
-		}
-
-		foundation.RunCommandWithArgs(ctx, "dotnet", args)
-
-	case "pack": // Pack the NuGet package.
-
-		// Minimal example with defaults.
-		// image: extensions/dotnet:stable
-		// action: pack
-
-		// Customizations.
-		// image: extensions/dotnet:stable
-		// action: pack
-		// force-restore: true
-		// force-build: true
-		// configuration: Debug
-		// versionSuffix: 5
-
-		log.Printf("Packing the nuget package(s)...\n")
-
-		args := []string{
-			"pack",
-			"--configuration",
-			*configuration,
-		}
-
-		if *buildVersion != "" {
-			args = append(args, fmt.Sprintf("/p:Version=%s", *buildVersion))
-		}
-
-		if !*forceRestore {
-			args = append(args, "--no-restore")
-		}
-
-		if !*forceBuild {
-			args = append(args, "--no-build")
-		}
-
-		foundation.RunCommandWithArgs(ctx, "dotnet", args)
-
-	case "push-nuget": // Pushes the package(s) to NuGet.
-
-		// Minimal example with defaults.
-		// image: extensions/dotnet:stable
-		// action: push-nuget
-
-		// Customizations.
-		// image: extensions/dotnet:stable
-		// action: push-nuget
-		// packagesFolder: MyProject/BuildOutput
-		// nugetServerUrl: https://nuget.mycompany.com
-		// nugetServerApikey: 3a4cdeca-3d5b-41a2-ac59-ae4b5c5eaece
-		// nugetSkipDuplicate: true
-
-		log.Printf("Publishing the nuget package(s)...\n")
-
-		type nugetCredentials struct {
-			url string
-			key string
+	// with allSolutions the solutions are discovered when running the action
+	if !cfg.AllSolutions {
+		cfg.Solution, err = findSolution(workingDir, cfg.SolutionFile)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Couldn't read the solution file.")
 		}
 
-		var nugetPushCredentials []nugetCredentials
-		// Determine the NuGet server credentials
-		// If nugetServerURL and nugetServerAPIKey are explicitly specified, we use those.
-		// Otherwise, we automatically push to GitHub.
-		if *nugetServerURL == "" || *nugetServerAPIKey == "" {
-			// use mounted credential file if present instead of relying on an envvar
-			//nolint:errorcheck
-			if runtime.GOOS == "windows" {
-				*nugetServerCredentialsJSONPath = "C:" + *nugetServerCredentialsJSONPath
-			}
-
-			if foundation.FileExists(*nugetServerCredentialsJSONPath) {
-				url, key := getNugetServerCredentialsFromFile(*nugetServerCredentialsJSONPath, "github-nuget")
-				nugetPushCredentials = append(nugetPushCredentials, nugetCredentials{url: url, key: key})
-			} else {
-				log.Fatal().Msg("The NuGet server URL and API key have to be specified to push a package.")
-			}
+		if cfg.Solution == nil {
+			log.Printf("Unknown solution")
 		} else {
-			nugetPushCredentials = append(nugetPushCredentials, nugetCredentials{url: *nugetServerURL, key: *nugetServerAPIKey})
+			cfg.SolutionName = cfg.Solution.Name
+			log.Printf("Solution name: %s", cfg.SolutionName)
 		}
+	}
 
-		packagesBasePath := *packagesFolder
-		if packagesBasePath == "" {
-			packagesBasePath = filepath.Join(workingDir, "src")
-		}
-
-		var files []string
-		err := filepath.Walk(packagesBasePath, func(path string, f os.FileInfo, _ error) error {
-			if !f.IsDir() {
-				if filepath.Ext(path) == ".nupkg" {
-					files = append(files, path)
-				}
-			}
-
-			return nil
-		})
-
+	if cfg.DryRun {
+		commands, err := planAction(ctx, cfg)
+		log.Info().Msg(formatPlan(cfg, commands))
 		if err != nil {
-			log.Fatal().Err(err).Msg("An error occurred while searching for .nupkg files.")
-		}
-
-		if len(files) == 0 {
-			log.Fatal().Msg("No .nupkg files were found.")
+			log.Fatal().Err(err).Msgf("The %v action would fail.", cfg.Action)
 		}
-
-		args1 := []string{
-			"nuget",
-			"push",
-		}
-
-		if *nugetSkipDuplicate {
-			args1 = append(args1, "--skip-duplicate")
-		}
-
-		for i := 0; i < len(files); i++ {
-			var argsForPackage []string
-			argsForPackage = append(argsForPackage, args1...)
-			argsForPackage = append(argsForPackage, files[i])
-
-			for _, cred := range nugetPushCredentials {
-				var argsForServer []string
-				argsForServer = append(argsForServer, argsForPackage...)
-				argsForServer = append(argsForServer, "--source", cred.url, "--api-key", cred.key)
-
-				// log the command for dotnet with argsForServer
-
-				flagsString := strings.Join(argsForServer, " ")
-				log.Printf("dotnet %v", flagsString)
-				foundation.RunCommandWithArgsWithoutLog(ctx, "dotnet", argsForServer)
-			}
-		}
-
-	default:
-		log.Fatal().Msg("Set `action: <action>` on this step to restore, build, test, unit-test, integration-test or publish.")
-	}
-}
-
-func getNugetServerCredentialsFromFile(credentialsFilePath string, serverName string) (serverURL string, APIKey string) {
-	log.Printf("Unmarshalling credentials...")
-
-	log.Info().Msgf("Reading credentials from file at path %v...", credentialsFilePath)
-	credentialsFileContent, err := os.ReadFile(credentialsFilePath)
-	if err != nil {
-		log.Fatal().Msgf("Failed reading credential file at path %v.", credentialsFilePath)
-	}
-
-	var credentials []NugetServerCredentials
-	err = json.Unmarshal(credentialsFileContent, &credentials)
-	if err != nil {
-		log.Fatal().Err(err).Msg("Failed unmarshalling credentials")
+		return
 	}
 
-	if len(credentials) == 0 {
-		log.Fatal().Msg("There are no credentials specified.")
-	}
+	cfg.Report = NewReport(cfg)
+	err = runAction(ctx, NewCommandRunner(), cfg)
+	cfg.Report.finish(err)
 
-	if serverName != "" {
-		credential := GetNugetServerCredentialsByName(credentials, serverName)
-		if credential == nil {
-			log.Fatal().Msgf("The NuGet Server credential with the name %v does not exist.", serverName)
+	if cfg.ReportPath != "" {
+		reportPath := cfg.ReportPath
+		if !filepath.IsAbs(reportPath) {
+			reportPath = filepath.Join(workingDir, reportPath)
 		}
-
-		serverURL = credential.AdditionalProperties.APIURL
-		APIKey = credential.AdditionalProperties.APIKey
-	} else {
-		// Just pick the first
-		credential := credentials[0]
-
-		serverURL = credential.AdditionalProperties.APIURL
-		APIKey = credential.AdditionalProperties.APIKey
-	}
-
-	return
-}
-
-// Returns the name of the .NET Core solution in this repository, based on the name of the solution file. If it cannot find a solution file, it returns an empty string.
-func getSolutionName() (string, error) {
-	files, err := os.ReadDir(".")
-
-	if err == nil {
-		for _, f := range files {
-			if strings.HasSuffix(f.Name(), ".sln") {
-				return strings.TrimSuffix(f.Name(), ".sln"), nil
-			}
+		if writeErr := cfg.Report.Write(reportPath); writeErr != nil {
+			log.Warn().Err(writeErr).Msg("Couldn't write the report.")
 		}
-
-		return "", nil
-	}
-
-	return "", err
-}
-
-// Runs the unit tests for all projects in the ./test folder which have the passed in suffix in their name.
-func runTests(ctx context.Context, projectSuffix string, extraArgs ...string) {
-	// Minimal example with defaults.
-	// image: extensions/dotnet:stable
-	// action: build
-
-	// Customizations.
-	// image: extensions/dotnet:stable
-	// action: build
-	// configuration: Debug
-	// versionSuffix: 5
-
-	args := []string{
-		"test",
-		"--configuration",
-		*configuration,
-	}
-
-	if !*forceRestore {
-		args = append(args, "--no-restore")
-	}
-
-	if !*forceBuild {
-		args = append(args, "--no-build")
 	}
 
-	args = append(args, extraArgs...)
-
-	files, err := os.ReadDir("./test")
-
-	if err == nil {
-		for _, f := range files {
-			if f.IsDir() && strings.HasSuffix(f.Name(), projectSuffix) {
-				log.Printf("Running tests for ./test/%s...\n", f.Name())
-
-				argsForProject := make([]string, len(args)+1)
-				copy(argsForProject, args)
-
-				argsForProject = append(argsForProject, fmt.Sprintf("./test/%s", f.Name()))
-
-				foundation.RunCommandWithArgs(ctx, "dotnet", argsForProject)
-			}
-		}
-	} else if !os.IsNotExist(err) { // If we got an error just because the "test" folder doesn't exist, that's fine, we can ignore. We only fail with an error if it was something else.
-		log.Fatal().Err(err).Msg("Failed to read subdirectories under ./test.")
+	if err != nil {
+		log.Fatal().Err(err).Msgf("The %v action failed.", cfg.Action)
 	}
 }
 
-func findActualNugetFileName(fileName string) string {
-	files, err := os.ReadDir(".")
+func findActualNugetFileName(dir, fileName string) string {
+	files, err := os.ReadDir(dir)
 	if err == nil {
 		for _, f := range files {
 			if strings.EqualFold(f.Name(), fileName) {
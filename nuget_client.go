@@ -0,0 +1,207 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// NugetClient looks up the versions of packages on a NuGet server with the v3 protocol
+type NugetClient struct {
+	serviceIndexURL string
+	username        string
+	apiKey          string
+	httpClient      *http.Client
+	// host is the host of the service index, the only one which gets the credentials
+	host string
+	// resources holds the resources of the service index once it's read
+	resources []NugetServiceResource
+}
+
+// NugetServiceIndex is the entry point of a NuGet v3 server, listing the resources it supports
+type NugetServiceIndex struct {
+	Version   string                 `json:"version"`
+	Resources []NugetServiceResource `json:"resources"`
+}
+
+// NugetServiceResource is a resource of the service index
+type NugetServiceResource struct {
+	ID   string `json:"@id"`
+	Type string `json:"@type"`
+}
+
+type nugetFlatContainerIndex struct {
+	Versions []string `json:"versions"`
+}
+
+type nugetRegistrationIndex struct {
+	Items []nugetRegistrationPage `json:"items"`
+}
+
+type nugetRegistrationPage struct {
+	ID string `json:"@id"`
+	// Items are left out by servers which only link the page, in which case it's fetched by its id
+	Items []struct {
+		CatalogEntry struct {
+			Version string `json:"version"`
+		} `json:"catalogEntry"`
+	} `json:"items"`
+}
+
+// the resource types of the service index with the versions of a package, in order of preference
+var (
+	flatContainerResourceTypes = []string{"PackageBaseAddress/3.0.0"}
+	registrationResourceTypes  = []string{"RegistrationsBaseUrl/3.6.0", "RegistrationsBaseUrl/3.4.0", "RegistrationsBaseUrl/3.0.0-rc", "RegistrationsBaseUrl/3.0.0-beta", "RegistrationsBaseUrl"}
+)
+
+// NewNugetClient returns a client for the server of the credential, which authenticates with the username and api key like the restore does.
+// The resources of the service index can be on other hosts, like a CDN, which don't get the credentials.
+func NewNugetClient(credential *NugetServerCredentials) *NugetClient {
+	host := ""
+	if u, err := url.Parse(credential.AdditionalProperties.APIURL); err == nil {
+		host = u.Host
+	}
+
+	return &NugetClient{
+		serviceIndexURL: credential.AdditionalProperties.APIURL,
+		username:        credential.getUsername(),
+		apiKey:          credential.AdditionalProperties.APIKey,
+		httpClient:      &http.Client{Timeout: 30 * time.Second},
+		host:            host,
+	}
+}
+
+// GetPackageVersions returns the versions of the package on the server, from the flat container or else from the registrations, or nil if the server doesn't have the package
+func (c *NugetClient) GetPackageVersions(ctx context.Context, id string) ([]string, error) {
+	resources, err := c.getResources(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	lowerID := strings.ToLower(id)
+
+	if baseURL := findServiceResource(resources, flatContainerResourceTypes); baseURL != "" {
+		var index nugetFlatContainerIndex
+		found, err := c.getJSON(ctx, strings.TrimSuffix(baseURL, "/")+"/"+lowerID+"/index.json", &index)
+		if err != nil || !found {
+			return nil, err
+		}
+
+		return index.Versions, nil
+	}
+
+	if baseURL := findServiceResource(resources, registrationResourceTypes); baseURL != "" {
+		var index nugetRegistrationIndex
+		found, err := c.getJSON(ctx, strings.TrimSuffix(baseURL, "/")+"/"+lowerID+"/index.json", &index)
+		if err != nil || !found {
+			return nil, err
+		}
+
+		var versions []string
+		for _, page := range index.Items {
+			if page.Items == nil {
+				_, err := c.getJSON(ctx, page.ID, &page)
+				if err != nil {
+					return nil, err
+				}
+			}
+			for _, leaf := range page.Items {
+				versions = append(versions, leaf.CatalogEntry.Version)
+			}
+		}
+
+		return versions, nil
+	}
+
+	return nil, fmt.Errorf("the service index %v has neither a %v nor a %v resource to look up the package versions", c.serviceIndexURL, flatContainerResourceTypes[0], registrationResourceTypes[0])
+}
+
+// PackageVersionExists returns whether the server has the version of the package, comparing the versions like NuGet normalizes them.
+func (c *NugetClient) PackageVersionExists(ctx context.Context, id, version string) (bool, error) {
+	versions, err := c.GetPackageVersions(ctx, id)
+	if err != nil {
+		return false, err
+	}
+
+	for _, v := range versions {
+		if normalizePackageVersion(v) == normalizePackageVersion(version) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// Returns the resources of the service index, which is read only once.
+func (c *NugetClient) getResources(ctx context.Context) ([]NugetServiceResource, error) {
+	if c.resources != nil {
+		return c.resources, nil
+	}
+
+	var index NugetServiceIndex
+	found, err := c.getJSON(ctx, c.serviceIndexURL, &index)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, fmt.Errorf("the service index %v doesn't exist, the url of a NuGet v3 server ends with index.json", c.serviceIndexURL)
+	}
+	if !strings.HasPrefix(index.Version, "3.") {
+		return nil, fmt.Errorf("the service index %v has version %v, while only version 3 is supported", c.serviceIndexURL, index.Version)
+	}
+
+	c.resources = index.Resources
+
+	return c.resources, nil
+}
+
+// Returns the id of the first resource with one of the types, which are in order of preference, or an empty string if there's none.
+func findServiceResource(resources []NugetServiceResource, types []string) string {
+	for _, t := range types {
+		for _, r := range resources {
+			if r.Type == t {
+				return r.ID
+			}
+		}
+	}
+
+	return ""
+}
+
+// Gets the json document at the url into the value, and returns false if it doesn't exist.
+func (c *NugetClient) getJSON(ctx context.Context, documentURL string, value interface{}) (bool, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, documentURL, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed creating the request for %v: %w", documentURL, err)
+	}
+	request.Header.Set("Accept", "application/json")
+	if c.apiKey != "" && c.host != "" && strings.EqualFold(request.URL.Host, c.host) {
+		request.SetBasicAuth(c.username, c.apiKey)
+	}
+
+	response, err := c.httpClient.Do(request)
+	if err != nil {
+		return false, fmt.Errorf("failed requesting %v: %w", documentURL, err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode == http.StatusNotFound {
+		return false, nil
+	}
+	if response.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(response.Body, 512))
+		return false, fmt.Errorf("requesting %v failed with %v: %v", documentURL, response.Status, strings.TrimSpace(string(body)))
+	}
+
+	err = json.NewDecoder(response.Body).Decode(value)
+	if err != nil {
+		return false, fmt.Errorf("failed parsing the response of %v: %w", documentURL, err)
+	}
+
+	return true, nil
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Starts a stand-in NuGet v3 feed which serves the json documents by path, with the server url replacing {url} in them, and answers 404 for any other path.
+func newTestFeed(t *testing.T, documents map[string]string) *httptest.Server {
+	t.Helper()
+
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		document, ok := documents[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, strings.ReplaceAll(document, "{url}", server.URL))
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func newTestNugetClient(server *httptest.Server) *NugetClient {
+	return NewNugetClient(&NugetServerCredentials{
+		Name: "internal-nuget",
+		AdditionalProperties: NugetServerCredentialsAdditionalProperties{
+			APIURL: server.URL + "/v3/index.json",
+			APIKey: "internal-secret-key",
+		},
+	})
+}
+
+func TestNugetClientGetPackageVersions(t *testing.T) {
+
+	t.Run("ReadsFlatContainer", func(t *testing.T) {
+		server := newTestFeed(t, map[string]string{
+			"/v3/index.json": `{"version": "3.0.0", "resources": [
+				{"@id": "{url}/v3/registration/", "@type": "RegistrationsBaseUrl/3.6.0"},
+				{"@id": "{url}/v3/flatcontainer/", "@type": "PackageBaseAddress/3.0.0"}]}`,
+			"/v3/flatcontainer/acme.lib/index.json": `{"versions": ["1.0.0", "1.2.3"]}`,
+		})
+		client := newTestNugetClient(server)
+
+		versions, err := client.GetPackageVersions(context.Background(), "Acme.Lib")
+
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(versions, []string{"1.0.0", "1.2.3"}) {
+			t.Errorf("unexpected versions %v", versions)
+		}
+	})
+
+	t.Run("ReadsRegistrationsWithLinkedPages", func(t *testing.T) {
+		server := newTestFeed(t, map[string]string{
+			"/v3/index.json": `{"version": "3.0.0", "resources": [
+				{"@id": "{url}/v3/registration", "@type": "RegistrationsBaseUrl"}]}`,
+			"/v3/registration/acme.lib/index.json": `{"items": [
+				{"@id": "{url}/v3/registration/acme.lib/page1.json", "items": [{"catalogEntry": {"version": "1.0.0"}}]},
+				{"@id": "{url}/v3/registration/acme.lib/page2.json"}]}`,
+			"/v3/registration/acme.lib/page2.json": `{"@id": "{url}/v3/registration/acme.lib/page2.json", "items": [{"catalogEntry": {"version": "1.2.3+build.5"}}]}`,
+		})
+		client := newTestNugetClient(server)
+
+		versions, err := client.GetPackageVersions(context.Background(), "Acme.Lib")
+
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(versions, []string{"1.0.0", "1.2.3+build.5"}) {
+			t.Errorf("unexpected versions %v", versions)
+		}
+	})
+
+	t.Run("ReturnsNothingForUnknownPackage", func(t *testing.T) {
+		server := newTestFeed(t, map[string]string{
+			"/v3/index.json": `{"version": "3.0.0", "resources": [{"@id": "{url}/v3/flatcontainer/", "@type": "PackageBaseAddress/3.0.0"}]}`,
+		})
+		client := newTestNugetClient(server)
+
+		versions, err := client.GetPackageVersions(context.Background(), "Acme.Unknown")
+
+		if err != nil {
+			t.Fatal(err)
+		}
+		if versions != nil {
+			t.Errorf("expected no versions, got %v", versions)
+		}
+	})
+
+	t.Run("FailsWithoutServiceIndex", func(t *testing.T) {
+		server := newTestFeed(t, map[string]string{})
+		client := newTestNugetClient(server)
+
+		_, err := client.GetPackageVersions(context.Background(), "Acme.Lib")
+
+		if err == nil || !strings.Contains(err.Error(), "doesn't exist") {
+			t.Errorf("expected an error about the missing service index, got %v", err)
+		}
+	})
+}
+
+func TestNugetClientPackageVersionExists(t *testing.T) {
+	server := newTestFeed(t, map[string]string{
+		"/v3/index.json":                        `{"version": "3.0.0", "resources": [{"@id": "{url}/v3/flatcontainer/", "@type": "PackageBaseAddress/3.0.0"}]}`,
+		"/v3/flatcontainer/acme.lib/index.json": `{"versions": ["1.0.0", "1.2.3-beta.1"]}`,
+	})
+	client := newTestNugetClient(server)
+
+	tests := []struct {
+		version string
+		exists  bool
+	}{
+		{"1.0.0", true},
+		{"1.0", true},
+		{"1.0.0.0", true},
+		{"1.2.3-BETA.1", true},
+		{"1.2.3", false},
+	}
+
+	for _, tt := range tests {
+		exists, err := client.PackageVersionExists(context.Background(), "Acme.Lib", tt.version)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if exists != tt.exists {
+			t.Errorf("expected %v for version %v, got %v", tt.exists, tt.version, exists)
+		}
+	}
+}
+
+func TestNugetClientSendsCredentialsOnlyToServiceIndexHost(t *testing.T) {
+	var cdnAuthorization string
+	cdn := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cdnAuthorization = r.Header.Get("Authorization")
+		fmt.Fprint(w, `{"versions": ["1.2.3"]}`)
+	}))
+	t.Cleanup(cdn.Close)
+	var indexUsername, indexPassword string
+	feed := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		indexUsername, indexPassword, _ = r.BasicAuth()
+		fmt.Fprintf(w, `{"version": "3.0.0", "resources": [{"@id": "%v/flatcontainer/", "@type": "PackageBaseAddress/3.0.0"}]}`, cdn.URL)
+	}))
+	t.Cleanup(feed.Close)
+	client := newTestNugetClient(feed)
+
+	exists, err := client.PackageVersionExists(context.Background(), "Acme.Lib", "1.2.3")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !exists {
+		t.Errorf("expected the version to exist")
+	}
+	if indexUsername != "internal-nuget" || indexPassword != "internal-secret-key" {
+		t.Errorf("expected the service index to get the credentials, got %q and %q", indexUsername, indexPassword)
+	}
+	if cdnAuthorization != "" {
+		t.Errorf("expected the resource on another host not to get the credentials, got %q", cdnAuthorization)
+	}
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"html"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// NugetConfig holds the package sources of a nuget.config file
+type NugetConfig struct {
+	Sources []NugetSource
+	// HasPackageSourceMapping is true if the file already maps packages to sources
+	HasPackageSourceMapping bool
+}
+
+// NugetSource is a package source of a nuget.config file
+type NugetSource struct {
+	Name string
+	URL  string
+}
+
+type nugetConfigXML struct {
+	PackageSources struct {
+		Sources []struct {
+			Key   string `xml:"key,attr"`
+			Value string `xml:"value,attr"`
+		} `xml:"add"`
+	} `xml:"packageSources"`
+	PackageSourceMapping *struct{} `xml:"packageSourceMapping"`
+}
+
+// the config used as a base for the generated one when the repository doesn't have a nuget.config, with the source NuGet adds by default
+const defaultNugetConfig = `<?xml version="1.0" encoding="utf-8"?>
+<configuration>
+  <packageSources>
+    <add key="nuget.org" value="https://api.nuget.org/v3/index.json" protocolVersion="3" />
+  </packageSources>
+</configuration>
+`
+
+// ReadNugetConfig reads the package sources from a nuget.config file
+func ReadNugetConfig(configPath string) (*NugetConfig, error) {
+	content, err := os.ReadFile(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed reading nuget config file %v: %w", configPath, err)
+	}
+
+	return parseNugetConfig(content, configPath)
+}
+
+// Parses the content of a nuget config file, the name is only used in errors.
+func parseNugetConfig(content []byte, name string) (*NugetConfig, error) {
+	var parsed nugetConfigXML
+	err := xml.Unmarshal(content, &parsed)
+	if err != nil {
+		return nil, fmt.Errorf("failed parsing nuget config file %v: %w", name, err)
+	}
+
+	config := &NugetConfig{HasPackageSourceMapping: parsed.PackageSourceMapping != nil}
+	for _, s := range parsed.PackageSources.Sources {
+		config.Sources = append(config.Sources, NugetSource{Name: s.Key, URL: s.Value})
+	}
+
+	return config, nil
+}
+
+// Returns the source with the name or else with the url, ignoring case like NuGet does, or nil if the config doesn't contain it.
+func (c *NugetConfig) findSource(name, url string) *NugetSource {
+	for i, s := range c.Sources {
+		if strings.EqualFold(s.Name, name) {
+			return &c.Sources[i]
+		}
+	}
+	for i, s := range c.Sources {
+		if strings.EqualFold(strings.TrimSuffix(s.URL, "/"), strings.TrimSuffix(url, "/")) {
+			return &c.Sources[i]
+		}
+	}
+
+	return nil
+}
+
+// Returns the package source mapping of the packageSourceMapping label as a packageSourceMapping section, with the sources in alphabetical order.
+func renderPackageSourceMapping(mapping map[string][]string) string {
+	names := make([]string, 0, len(mapping))
+	for name := range mapping {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var sb strings.Builder
+	sb.WriteString("  <packageSourceMapping>\n")
+	for _, name := range names {
+		fmt.Fprintf(&sb, "    <packageSource key=\"%v\">\n", xmlEscape(name))
+		for _, pattern := range mapping[name] {
+			fmt.Fprintf(&sb, "      <package pattern=\"%v\" />\n", xmlEscape(pattern))
+		}
+		sb.WriteString("    </packageSource>\n")
+	}
+	sb.WriteString("  </packageSourceMapping>\n")
+
+	return sb.String()
+}
+
+// Returns a nuget config with the folder as its only source, clearing the sources of the user and machine wide configs so nothing is restored from a remote source.
+func renderOfflineNugetConfig(sourceName, folder string) string {
+	return fmt.Sprintf(`<?xml version="1.0" encoding="utf-8"?>
+<configuration>
+  <packageSources>
+    <clear />
+    <add key="%v" value="%v" />
+  </packageSources>
+</configuration>
+`, xmlEscape(sourceName), xmlEscape(folder))
+}
+
+// Returns the path of the nuget.config NuGet uses for the directory, the one in the directory itself or else in the closest of its parent directories, or an empty string if there's none.
+func findNugetConfigPath(dir string) string {
+	for {
+		if actualFileName := findActualNugetFileName(dir, "nuget.config"); actualFileName != "" {
+			return filepath.Join(dir, actualFileName)
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+var (
+	nugetConfigPathSectionRegex = regexp.MustCompile(`(?s)<(packageSources|fallbackPackageFolders|config)(?:\s[^>/]*)?>.*?</(?:packageSources|fallbackPackageFolders|config)>`)
+	nugetConfigAddRegex         = regexp.MustCompile(`<add\b[^>]*>`)
+	nugetConfigKeyRegex         = regexp.MustCompile(`\bkey\s*=\s*(?:"([^"]*)"|'([^']*)')`)
+	nugetConfigValueRegex       = regexp.MustCompile(`\bvalue\s*=\s*(?:"[^"]*"|'[^']*')`)
+	absolutePathRegex           = regexp.MustCompile(`^(?:[A-Za-z]:|[\\/%$~])`)
+)
+
+// the keys of the config section which hold a path
+var nugetConfigPathKeys = []string{"globalPackagesFolder", "repositoryPath"}
+
+// Rewrites the relative paths of the local sources, fallback folders and package folders in the content of a nuget.config to absolute ones,
+// because NuGet resolves them against the directory of the config file, which is another one for a copy.
+func makeNugetConfigPathsAbsolute(content []byte, dir string) []byte {
+	return nugetConfigPathSectionRegex.ReplaceAllFunc(content, func(section []byte) []byte {
+		isConfig := nugetConfigPathSectionRegex.FindSubmatch(section)[1][0] == 'c'
+
+		return nugetConfigAddRegex.ReplaceAllFunc(section, func(add []byte) []byte {
+			if isConfig {
+				key := nugetConfigKeyRegex.FindSubmatch(add)
+				if key == nil || !containsFold(nugetConfigPathKeys, string(key[1])+string(key[2])) {
+					return add
+				}
+			}
+
+			return nugetConfigValueRegex.ReplaceAllFunc(add, func(attribute []byte) []byte {
+				quote := attribute[len(attribute)-1]
+				start := strings.IndexByte(string(attribute), quote)
+				value := html.UnescapeString(string(attribute[start+1 : len(attribute)-1]))
+				if value == "" || strings.Contains(value, "://") || absolutePathRegex.MatchString(value) {
+					return attribute
+				}
+
+				absolute := filepath.Join(dir, filepath.FromSlash(strings.ReplaceAll(value, "\\", "/")))
+				return []byte(fmt.Sprintf(`value="%v"`, xmlEscape(absolute)))
+			})
+		})
+	})
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+
+	return false
+}
+
+var emptyConfigurationRegex = regexp.MustCompile(`<configuration\s*/>`)
+
+// Adds a rendered section to the content of a nuget.config file, as the last child of the configuration element.
+func addNugetConfigSection(content []byte, section string) ([]byte, error) {
+	s := string(content)
+	if loc := emptyConfigurationRegex.FindStringIndex(s); loc != nil {
+		return []byte(s[:loc[0]] + "<configuration>\n" + section + "</configuration>" + s[loc[1]:]), nil
+	}
+
+	end := strings.LastIndex(s, "</configuration>")
+	if end < 0 {
+		return nil, fmt.Errorf("the nuget config doesn't have a configuration element")
+	}
+
+	return []byte(s[:end] + section + s[end:]), nil
+}
+
+func xmlEscape(s string) string {
+	var sb strings.Builder
+	xml.EscapeText(&sb, []byte(s))
+
+	return sb.String()
+}
+
+// Writes the content as nuget config to a new temporary directory, so sources can be added to it without touching a file in the repository.
+// The returned function removes the directory again.
+func writeNugetConfigToTempDir(content []byte) (string, func(), error) {
+	tempDir, err := os.MkdirTemp("", "estafette-nuget-")
+	if err != nil {
+		return "", nil, fmt.Errorf("failed creating a temporary directory for the nuget config: %w", err)
+	}
+	cleanup := func() {
+		os.RemoveAll(tempDir)
+	}
+
+	tempPath := filepath.Join(tempDir, "NuGet.Config")
+	err = os.WriteFile(tempPath, content, 0600)
+	if err != nil {
+		cleanup()
+		return "", nil, fmt.Errorf("failed writing the temporary nuget config: %w", err)
+	}
+
+	return tempPath, cleanup, nil
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadNugetConfig(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "nuget.config")
+	err := os.WriteFile(configPath, []byte(`<?xml version="1.0" encoding="utf-8"?>
+<configuration>
+  <packageSources>
+    <clear />
+    <add key="nuget.org" value="https://api.nuget.org/v3/index.json" protocolVersion="3" />
+    <add key="Acme" value="https://nuget.acme.com/v3/index.json" />
+  </packageSources>
+  <config>
+    <add key="globalPackagesFolder" value=".packages" />
+  </config>
+</configuration>`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := ReadNugetConfig(configPath)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []NugetSource{
+		{Name: "nuget.org", URL: "https://api.nuget.org/v3/index.json"},
+		{Name: "Acme", URL: "https://nuget.acme.com/v3/index.json"},
+	}
+	if !reflect.DeepEqual(config.Sources, expected) {
+		t.Errorf("unexpected sources %+v", config.Sources)
+	}
+	if s := config.findSource("acme", ""); s == nil || s.Name != "Acme" {
+		t.Errorf("expected to find the source by name ignoring case, got %+v", s)
+	}
+	if s := config.findSource("github-nuget", "https://api.nuget.org/v3/index.json/"); s == nil || s.Name != "nuget.org" {
+		t.Errorf("expected to find the source by url, got %+v", s)
+	}
+	if s := config.findSource("github-nuget", "https://nuget.pkg.github.com/acme/index.json"); s != nil {
+		t.Errorf("expected no source, got %+v", s)
+	}
+}
+
+func TestAddNugetConfigSection(t *testing.T) {
+	section := renderPackageSourceMapping(map[string][]string{"nuget.org": {"*"}})
+
+	for input, expected := range map[string]string{
+		`<configuration />`: "<configuration>\n" + section + "</configuration>",
+		"<configuration>\n  <config />\n</configuration>\n": "<configuration>\n  <config />\n" + section + "</configuration>\n",
+	} {
+		actual, err := addNugetConfigSection([]byte(input), section)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(actual) != expected {
+			t.Errorf("unexpected content for %q:\n%v", input, string(actual))
+		}
+	}
+}
+
+func TestMakeNugetConfigPathsAbsolute(t *testing.T) {
+	dir := filepath.Join(string(filepath.Separator), "repo", "src")
+	content := `<configuration>
+  <packageSources>
+    <add key="nuget.org" value="https://api.nuget.org/v3/index.json" />
+    <add key="local" value="./feed" />
+    <add key="shared" value="..\shared\feed" />
+    <add key="mirror" value="/mirror/nuget" />
+  </packageSources>
+  <fallbackPackageFolders>
+    <add key="offline" value="fallback" />
+  </fallbackPackageFolders>
+  <config>
+    <add key="globalPackagesFolder" value=".packages" />
+    <add key="signatureValidationMode" value="require" />
+  </config>
+</configuration>`
+
+	actual := string(makeNugetConfigPathsAbsolute([]byte(content), dir))
+
+	expected := strings.NewReplacer(
+		`value="./feed"`, `value="`+filepath.Join(dir, "feed")+`"`,
+		`value="..\shared\feed"`, `value="`+filepath.Join(dir, "..", "shared", "feed")+`"`,
+		`value="fallback"`, `value="`+filepath.Join(dir, "fallback")+`"`,
+		`value=".packages"`, `value="`+filepath.Join(dir, ".packages")+`"`,
+	).Replace(content)
+	if actual != expected {
+		t.Errorf("unexpected content:\n%v", actual)
+	}
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Package is a .nupkg file, which is a zip archive with the nuspec at its root
+type Package struct {
+	Path   string
+	Nuspec *Nuspec
+	// Files are the paths of the entries of the archive
+	Files []string
+}
+
+// Nuspec is the manifest of a package
+type Nuspec struct {
+	Metadata NuspecMetadata `xml:"metadata"`
+}
+
+// NuspecMetadata holds the metadata of a package, as rendered by dotnet pack from the project properties
+type NuspecMetadata struct {
+	ID          string `xml:"id"`
+	Version     string `xml:"version"`
+	Authors     string `xml:"authors"`
+	Description string `xml:"description"`
+	License     struct {
+		Type  string `xml:"type,attr"`
+		Value string `xml:",chardata"`
+	} `xml:"license"`
+	Repository struct {
+		Type string `xml:"type,attr"`
+		URL  string `xml:"url,attr"`
+	} `xml:"repository"`
+	Readme string `xml:"readme"`
+}
+
+// ReadPackage reads the nuspec and the list of files of a package
+func ReadPackage(packagePath string) (*Package, error) {
+	archive, err := zip.OpenReader(packagePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed opening package %v: %w", packagePath, err)
+	}
+	defer archive.Close()
+
+	p := &Package{Path: packagePath}
+	for _, f := range archive.File {
+		p.Files = append(p.Files, f.Name)
+
+		if path.Dir(f.Name) != "." || !strings.EqualFold(path.Ext(f.Name), ".nuspec") {
+			continue
+		}
+		p.Nuspec, err = readNuspec(f)
+		if err != nil {
+			return nil, fmt.Errorf("failed reading the nuspec of package %v: %w", packagePath, err)
+		}
+	}
+
+	if p.Nuspec == nil {
+		return nil, fmt.Errorf("package %v doesn't have a nuspec", packagePath)
+	}
+
+	return p, nil
+}
+
+func readNuspec(f *zip.File) (*Nuspec, error) {
+	r, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var nuspec Nuspec
+	err = xml.Unmarshal(content, &nuspec)
+	if err != nil {
+		return nil, err
+	}
+
+	return &nuspec, nil
+}
+
+// the rules of the nugetPackageRules label, which the packages have to pass before they're pushed
+const (
+	packageRuleFileName       = "fileName"
+	packageRuleBuildVersion   = "buildVersion"
+	packageRuleAuthors        = "authors"
+	packageRuleLicense        = "license"
+	packageRuleRepository     = "repository"
+	packageRuleReadme         = "readme"
+	packageRuleTestAssemblies = "testAssemblies"
+)
+
+var packageRules = []string{packageRuleFileName, packageRuleBuildVersion, packageRuleAuthors, packageRuleLicense, packageRuleRepository, packageRuleReadme, packageRuleTestAssemblies}
+
+// test frameworks and test projects, which a package shouldn't ship
+var testAssemblyRegex = regexp.MustCompile(`(?i)(^|/)([^/]*\.(unit|integration)?tests?|xunit\.[^/]*|nunit\.framework|microsoft\.visualstudio\.testplatform\.[^/]*|mstest\.[^/]*)\.dll$`)
+
+// Returns the violations of the rules by the package, or nothing if it passes all of them.
+func (p *Package) validate(rules []string, buildVersion string) []string {
+	metadata := p.Nuspec.Metadata
+	enabled := func(rule string) bool {
+		for _, r := range rules {
+			if r == rule || r == "all" {
+				return true
+			}
+		}
+		return false
+	}
+
+	var violations []string
+	if enabled(packageRuleFileName) {
+		expected := metadata.ID + "." + normalizePackageVersion(metadata.Version) + ".nupkg"
+		if !strings.EqualFold(filepath.Base(p.Path), expected) {
+			violations = append(violations, fmt.Sprintf("the file name doesn't match id %v and version %v, which would make it %v", metadata.ID, metadata.Version, expected))
+		}
+	}
+	if enabled(packageRuleBuildVersion) && buildVersion != "" && normalizePackageVersion(metadata.Version) != normalizePackageVersion(buildVersion) {
+		violations = append(violations, fmt.Sprintf("version %v isn't the build version %v", metadata.Version, buildVersion))
+	}
+	if enabled(packageRuleAuthors) && strings.TrimSpace(metadata.Authors) == "" {
+		violations = append(violations, "the authors are missing")
+	}
+	if enabled(packageRuleLicense) && strings.TrimSpace(metadata.License.Value) == "" {
+		violations = append(violations, "the license expression or file is missing")
+	}
+	if enabled(packageRuleRepository) && strings.TrimSpace(metadata.Repository.URL) == "" {
+		violations = append(violations, "the repository url is missing")
+	}
+	if enabled(packageRuleReadme) {
+		switch {
+		case metadata.Readme == "":
+			violations = append(violations, "the readme is missing")
+		case !p.hasFile(metadata.Readme):
+			violations = append(violations, fmt.Sprintf("the readme %v isn't in the package", metadata.Readme))
+		}
+	}
+	if enabled(packageRuleTestAssemblies) {
+		for _, f := range p.Files {
+			if testAssemblyRegex.MatchString(f) {
+				violations = append(violations, fmt.Sprintf("it contains test assembly %v", f))
+			}
+		}
+	}
+
+	return violations
+}
+
+func (p *Package) hasFile(name string) bool {
+	name = strings.TrimPrefix(strings.ReplaceAll(name, `\`, "/"), "/")
+	for _, f := range p.Files {
+		if strings.EqualFold(f, name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Returns the version the way NuGet uses it in file names, without build metadata and with at least three parts, so 1.0+abc becomes 1.0.0.
+func normalizePackageVersion(version string) string {
+	version, _, _ = strings.Cut(strings.TrimSpace(version), "+")
+	release, prerelease, hasPrerelease := strings.Cut(version, "-")
+
+	parts := strings.Split(release, ".")
+	for len(parts) < 3 {
+		parts = append(parts, "0")
+	}
+	// a fourth part of zero is left out as well
+	if len(parts) == 4 && parts[3] == "0" {
+		parts = parts[:3]
+	}
+
+	normalized := strings.Join(parts, ".")
+	if hasPrerelease {
+		normalized += "-" + prerelease
+	}
+
+	return strings.ToLower(normalized)
+}
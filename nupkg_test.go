@@ -0,0 +1,140 @@
+package main
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testNuspec = `<?xml version="1.0" encoding="utf-8"?>
+<package xmlns="http://schemas.microsoft.com/packaging/2013/05/nuspec.xsd">
+  <metadata>
+    <id>Acme.Lib</id>
+    <version>1.2.3</version>
+    <authors>Acme</authors>
+    <description>Shared code of Acme.</description>
+    <license type="expression">MIT</license>
+    <repository type="git" url="https://github.com/acme/lib" />
+    <readme>README.md</readme>
+  </metadata>
+</package>`
+
+// Writes a package with the nuspec and empty files at the path.
+func writeNupkg(t *testing.T, packagePath, nuspec string, files ...string) {
+	t.Helper()
+
+	err := os.MkdirAll(filepath.Dir(packagePath), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Create(packagePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	archive := zip.NewWriter(f)
+	w, err := archive.Create("Acme.Lib.nuspec")
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write([]byte(nuspec))
+	for _, name := range files {
+		_, err := archive.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = archive.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReadPackage(t *testing.T) {
+	packagePath := filepath.Join(t.TempDir(), "Acme.Lib.1.2.3.nupkg")
+	writeNupkg(t, packagePath, testNuspec, "lib/net8.0/Acme.Lib.dll", "README.md")
+
+	p, err := ReadPackage(packagePath)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	metadata := p.Nuspec.Metadata
+	if metadata.ID != "Acme.Lib" || metadata.Version != "1.2.3" || metadata.License.Value != "MIT" || metadata.Repository.URL != "https://github.com/acme/lib" || metadata.Readme != "README.md" {
+		t.Errorf("unexpected metadata %+v", metadata)
+	}
+	if strings.Join(p.Files, ",") != "Acme.Lib.nuspec,lib/net8.0/Acme.Lib.dll,README.md" {
+		t.Errorf("unexpected files %v", p.Files)
+	}
+}
+
+func TestValidatePackage(t *testing.T) {
+
+	t.Run("PassesAllRules", func(t *testing.T) {
+		p := &Package{Path: "/src/Acme.Lib.1.2.3.nupkg", Files: []string{"lib/net8.0/Acme.Lib.dll", "README.md"}}
+		p.Nuspec = &Nuspec{}
+		p.Nuspec.Metadata.ID, p.Nuspec.Metadata.Version, p.Nuspec.Metadata.Authors = "Acme.Lib", "1.2.3+abc", "Acme"
+		p.Nuspec.Metadata.License.Value, p.Nuspec.Metadata.Repository.URL, p.Nuspec.Metadata.Readme = "MIT", "https://github.com/acme/lib", "README.md"
+
+		violations := p.validate([]string{"all"}, "1.2.3")
+
+		if len(violations) != 0 {
+			t.Errorf("expected no violations, got %v", violations)
+		}
+	})
+
+	t.Run("ReportsEveryViolation", func(t *testing.T) {
+		p := &Package{Path: "/src/Acme.Lib.1.0.0.nupkg", Files: []string{"lib/net8.0/Acme.Lib.dll", "lib/net8.0/Acme.Lib.Tests.dll", "lib/net8.0/xunit.core.dll"}}
+		p.Nuspec = &Nuspec{}
+		p.Nuspec.Metadata.ID, p.Nuspec.Metadata.Version, p.Nuspec.Metadata.Readme = "Acme.Lib", "1.2.3", "docs/README.md"
+
+		violations := p.validate([]string{"all"}, "1.2.4")
+
+		expected := []string{
+			"the file name doesn't match id Acme.Lib and version 1.2.3, which would make it Acme.Lib.1.2.3.nupkg",
+			"version 1.2.3 isn't the build version 1.2.4",
+			"the authors are missing",
+			"the license expression or file is missing",
+			"the repository url is missing",
+			"the readme docs/README.md isn't in the package",
+			"it contains test assembly lib/net8.0/Acme.Lib.Tests.dll",
+			"it contains test assembly lib/net8.0/xunit.core.dll",
+		}
+		if strings.Join(violations, "\n") != strings.Join(expected, "\n") {
+			t.Errorf("unexpected violations\nexpected:\n%v\nactual:\n%v", strings.Join(expected, "\n"), strings.Join(violations, "\n"))
+		}
+	})
+
+	t.Run("OnlyChecksEnabledRules", func(t *testing.T) {
+		p := &Package{Path: "/src/Acme.Lib.1.2.3.nupkg"}
+		p.Nuspec = &Nuspec{}
+		p.Nuspec.Metadata.ID, p.Nuspec.Metadata.Version = "Acme.Lib", "1.2.3"
+
+		violations := p.validate([]string{"fileName", "buildVersion"}, "")
+
+		if len(violations) != 0 {
+			t.Errorf("expected no violations, got %v", violations)
+		}
+	})
+}
+
+func TestNormalizePackageVersion(t *testing.T) {
+	tests := map[string]string{
+		"1.2.3":          "1.2.3",
+		"1.0":            "1.0.0",
+		"1.2.3.0":        "1.2.3",
+		"1.2.3.4":        "1.2.3.4",
+		"1.2.3-Beta.1":   "1.2.3-beta.1",
+		"1.2.3+sha.abc":  "1.2.3",
+		"2.0-rc+sha.abc": "2.0.0-rc",
+	}
+
+	for version, expected := range tests {
+		if actual := normalizePackageVersion(version); actual != expected {
+			t.Errorf("expected %v for %v, got %v", expected, version, actual)
+		}
+	}
+}
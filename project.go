@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ProjectFile is the content of a .csproj, .fsproj or .vbproj file that matters to the actions
+type ProjectFile struct {
+	Sdk string
+	// Properties holds the properties of all property groups, where a later definition wins like it does in msbuild
+	Properties        map[string]string
+	PackageReferences []PackageReference
+}
+
+// PackageReference is a PackageReference item of a project file
+type PackageReference struct {
+	Include string
+	Version string
+}
+
+type projectFileXML struct {
+	Sdk            string `xml:"Sdk,attr"`
+	PropertyGroups []struct {
+		Properties []struct {
+			XMLName xml.Name
+			Value   string `xml:",chardata"`
+		} `xml:",any"`
+	} `xml:"PropertyGroup"`
+	ItemGroups []struct {
+		PackageReferences []struct {
+			Include        string `xml:"Include,attr"`
+			Update         string `xml:"Update,attr"`
+			Version        string `xml:"Version,attr"`
+			VersionElement string `xml:"Version"`
+		} `xml:"PackageReference"`
+	} `xml:"ItemGroup"`
+}
+
+// the packages which make a project a test project if it doesn't set the IsTestProject property
+var testFrameworkPackages = []string{"Microsoft.NET.Test.Sdk", "xunit", "xunit.core", "NUnit", "MSTest", "MSTest.TestFramework"}
+
+// ReadProjectFile reads the properties and package references from a project file
+func ReadProjectFile(projectPath string) (*ProjectFile, error) {
+	content, err := os.ReadFile(projectPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed reading project file %v: %w", projectPath, err)
+	}
+
+	var parsed projectFileXML
+	err = xml.Unmarshal(content, &parsed)
+	if err != nil {
+		return nil, fmt.Errorf("failed parsing project file %v: %w", projectPath, err)
+	}
+
+	project := &ProjectFile{
+		Sdk:        parsed.Sdk,
+		Properties: map[string]string{},
+	}
+
+	for _, group := range parsed.PropertyGroups {
+		for _, property := range group.Properties {
+			project.Properties[property.XMLName.Local] = strings.TrimSpace(property.Value)
+		}
+	}
+
+	for _, group := range parsed.ItemGroups {
+		for _, reference := range group.PackageReferences {
+			if reference.Include == "" {
+				continue
+			}
+			version := reference.Version
+			if version == "" {
+				version = strings.TrimSpace(reference.VersionElement)
+			}
+			project.PackageReferences = append(project.PackageReferences, PackageReference{Include: reference.Include, Version: version})
+		}
+	}
+
+	return project, nil
+}
+
+// HasPackageReference returns true if the project references the package, ignoring case like NuGet does
+func (p *ProjectFile) HasPackageReference(packageID string) bool {
+	for _, reference := range p.PackageReferences {
+		if strings.EqualFold(reference.Include, packageID) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsTestProject returns the IsTestProject property if it's set, and otherwise whether the project references the test sdk or a test framework
+func (p *ProjectFile) IsTestProject() bool {
+	if value, ok := p.Properties["IsTestProject"]; ok && value != "" {
+		return strings.EqualFold(value, "true")
+	}
+
+	for _, packageID := range testFrameworkPackages {
+		if p.HasPackageReference(packageID) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Returns true if the path is a project file msbuild can read, as opposed to for example a docker-compose or sql project.
+func isReadableProjectFile(projectPath string) bool {
+	switch strings.ToLower(filepath.Ext(projectPath)) {
+	case ".csproj", ".fsproj", ".vbproj":
+		return true
+	}
+
+	return false
+}
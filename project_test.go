@@ -0,0 +1,43 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestReadProjectFile(t *testing.T) {
+	project, err := ReadProjectFile("testdata/categories/Acme.Bar.Tests/Acme.Bar.Tests.csproj")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if project.Sdk != "Microsoft.NET.Sdk" {
+		t.Errorf("expected sdk Microsoft.NET.Sdk, got %v", project.Sdk)
+	}
+	if project.Properties["TargetFramework"] != "net8.0" {
+		t.Errorf("expected target framework net8.0, got %v", project.Properties["TargetFramework"])
+	}
+	expected := []PackageReference{{Include: "MSTest.TestFramework", Version: "3.1.1"}, {Include: "MSTest.TestAdapter", Version: "3.1.1"}}
+	if !reflect.DeepEqual(project.PackageReferences, expected) {
+		t.Errorf("unexpected package references %+v", project.PackageReferences)
+	}
+}
+
+func TestIsTestProject(t *testing.T) {
+	for path, expected := range map[string]bool{
+		"testdata/webservice/src/Acme.FooApi.WebService/Acme.FooApi.WebService.csproj": false,
+		"testdata/webservice/test/Acme.FooApi.UnitTests/Acme.FooApi.UnitTests.csproj":  true,
+		"testdata/nested/tests/Acme.Shop.Api.Specs/Acme.Shop.Api.Specs.csproj":         true,
+		"testdata/categories/Acme.Bar/Acme.Bar.csproj":                                 false,
+		"testdata/categories/Acme.Bar.Tests/Acme.Bar.Tests.csproj":                     true,
+		"testdata/categories/Acme.Bar.Checks/Acme.Bar.Checks.csproj":                   true,
+	} {
+		project, err := ReadProjectFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if project.IsTestProject() != expected {
+			t.Errorf("expected IsTestProject of %v to be %v", path, expected)
+		}
+	}
+}
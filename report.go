@@ -0,0 +1,298 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	foundation "github.com/estafette/estafette-foundation"
+)
+
+// Report holds what an action executed and resolved, written to a file so later stages don't have to derive it again
+type Report struct {
+	mutex     sync.Mutex
+	Action    string            `json:"action"`
+	Status    string            `json:"status"`
+	Error     string            `json:"error,omitempty"`
+	StartedAt time.Time         `json:"startedAt"`
+	Duration  float64           `json:"durationSeconds"`
+	Solution  string            `json:"solution,omitempty"`
+	Version   string            `json:"version,omitempty"`
+	Values    map[string]string `json:"values,omitempty"`
+	Commands  []CommandReport   `json:"commands,omitempty"`
+	Artifacts []string          `json:"artifacts,omitempty"`
+	Tests     *TestCounts       `json:"tests,omitempty"`
+	// Solutions holds a report per solution when the action runs for all solutions
+	Solutions []*Report `json:"solutions,omitempty"`
+}
+
+// CommandReport is a single executed command with its secrets masked
+type CommandReport struct {
+	Command  string  `json:"command"`
+	Dir      string  `json:"dir,omitempty"`
+	Duration float64 `json:"durationSeconds"`
+	ExitCode int     `json:"exitCode"`
+}
+
+// TestCounts are the totals reported by dotnet test
+type TestCounts struct {
+	Total   int `json:"total"`
+	Passed  int `json:"passed"`
+	Failed  int `json:"failed"`
+	Skipped int `json:"skipped"`
+}
+
+// NewReport returns a report for the action in the config, which starts now
+func NewReport(cfg Config) *Report {
+	return &Report{
+		Action:    cfg.Action,
+		Status:    "running",
+		StartedAt: time.Now().UTC(),
+		Solution:  cfg.SolutionName,
+		Version:   cfg.BuildVersion,
+	}
+}
+
+// SetValue stores a resolved value like the published project or the output folder; it's safe to call on a nil report
+func (r *Report) SetValue(key, value string) {
+	if r == nil {
+		return
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.Values == nil {
+		r.Values = map[string]string{}
+	}
+	r.Values[key] = value
+}
+
+// AddArtifact stores a file or folder produced by the action; it's safe to call on a nil report
+func (r *Report) AddArtifact(path string) {
+	if r == nil {
+		return
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.Artifacts = append(r.Artifacts, path)
+}
+
+// AddTestCounts adds the counts of a test run to the totals; it's safe to call on a nil report
+func (r *Report) AddTestCounts(counts TestCounts) {
+	if r == nil {
+		return
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.Tests == nil {
+		r.Tests = &TestCounts{}
+	}
+	r.Tests.Total += counts.Total
+	r.Tests.Passed += counts.Passed
+	r.Tests.Failed += counts.Failed
+	r.Tests.Skipped += counts.Skipped
+}
+
+// Returns the artifacts of the report and of the reports per solution.
+func (r *Report) getArtifacts() []string {
+	if r == nil {
+		return nil
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	artifacts := append([]string{}, r.Artifacts...)
+	for _, solution := range r.Solutions {
+		artifacts = append(artifacts, solution.getArtifacts()...)
+	}
+
+	return artifacts
+}
+
+// ReadReport reads a report written by an earlier action
+func ReadReport(reportPath string) (*Report, error) {
+	content, err := os.ReadFile(reportPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed reading report %v: %w", reportPath, err)
+	}
+
+	var report Report
+	err = json.Unmarshal(content, &report)
+	if err != nil {
+		return nil, fmt.Errorf("failed parsing report %v: %w", reportPath, err)
+	}
+
+	return &report, nil
+}
+
+func (r *Report) addCommand(command CommandReport) {
+	if r == nil {
+		return
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.Commands = append(r.Commands, command)
+}
+
+func (r *Report) addSolution(solution *Report) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.Solutions = append(r.Solutions, solution)
+}
+
+// Sets the status and duration once the action is done.
+func (r *Report) finish(err error) {
+	if r == nil {
+		return
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.Duration = time.Since(r.StartedAt).Seconds()
+	r.Status = "succeeded"
+	if err != nil {
+		r.Status = "failed"
+		r.Error = err.Error()
+	}
+}
+
+// Write saves the report as json to the path, and as a dotenv file next to it with the .env extension
+func (r *Report) Write(reportPath string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	err := os.MkdirAll(filepath.Dir(reportPath), 0755)
+	if err != nil {
+		return fmt.Errorf("failed creating the directory for report %v: %w", reportPath, err)
+	}
+
+	content, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed marshalling the report: %w", err)
+	}
+	err = os.WriteFile(reportPath, content, 0644)
+	if err != nil {
+		return fmt.Errorf("failed writing report %v: %w", reportPath, err)
+	}
+
+	dotenvPath := strings.TrimSuffix(reportPath, filepath.Ext(reportPath)) + ".env"
+	err = os.WriteFile(dotenvPath, []byte(r.dotenv()), 0644)
+	if err != nil {
+		return fmt.Errorf("failed writing report %v: %w", dotenvPath, err)
+	}
+
+	return nil
+}
+
+// the prefix of the dotenv variables, as DOTNET_ variables configure the .NET SDK and runtime, and the sdk images already set DOTNET_VERSION
+const dotenvPrefix = "ESTAFETTE_DOTNET_"
+
+// Returns the main values of the report as ESTAFETTE_DOTNET_ prefixed variables, one per line and sorted, so they can be sourced by a shell.
+func (r *Report) dotenv() string {
+	variables := map[string]string{
+		dotenvPrefix + "ACTION":  r.Action,
+		dotenvPrefix + "STATUS":  r.Status,
+		dotenvPrefix + "VERSION": r.Version,
+	}
+	if r.Solution != "" {
+		variables[dotenvPrefix+"SOLUTION"] = r.Solution
+	}
+	for key, value := range r.Values {
+		variables[dotenvPrefix+foundation.ToUpperSnakeCase(key)] = value
+	}
+	if len(r.Artifacts) > 0 {
+		variables[dotenvPrefix+"ARTIFACTS"] = strings.Join(r.Artifacts, ",")
+	}
+	if r.Tests != nil {
+		variables[dotenvPrefix+"TESTS_TOTAL"] = strconv.Itoa(r.Tests.Total)
+		variables[dotenvPrefix+"TESTS_PASSED"] = strconv.Itoa(r.Tests.Passed)
+		variables[dotenvPrefix+"TESTS_FAILED"] = strconv.Itoa(r.Tests.Failed)
+		variables[dotenvPrefix+"TESTS_SKIPPED"] = strconv.Itoa(r.Tests.Skipped)
+	}
+
+	keys := make([]string, 0, len(variables))
+	for key := range variables {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var sb strings.Builder
+	for _, key := range keys {
+		fmt.Fprintf(&sb, "%v=%v\n", key, shellQuote(variables[key]))
+	}
+
+	return sb.String()
+}
+
+// Quotes the value in single quotes for a shell, which leaves everything in between as is, so $ and backticks aren't expanded when the dotenv file is sourced.
+func shellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+// reportingRunner records every command it runs in the report
+type reportingRunner struct {
+	runner CommandRunner
+	report *Report
+}
+
+func (r *reportingRunner) Run(ctx context.Context, command Command) error {
+	start := time.Now()
+	err := r.runner.Run(ctx, command)
+
+	exitCode := 0
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		exitCode = exitErr.ExitCode()
+	} else if err != nil {
+		exitCode = -1
+	}
+
+	r.report.addCommand(CommandReport{
+		Command:  command.String(),
+		Dir:      command.Dir,
+		Duration: time.Since(start).Seconds(),
+		ExitCode: exitCode,
+	})
+
+	return err
+}
+
+var testSummaryRegex = regexp.MustCompile(`Failed:\s*(\d+),\s*Passed:\s*(\d+),\s*Skipped:\s*(\d+),\s*Total:\s*(\d+)`)
+
+// Returns the sum of the test counts in the summary lines dotnet test prints per test assembly.
+func parseTestCounts(output string) TestCounts {
+	var counts TestCounts
+	for _, matches := range testSummaryRegex.FindAllStringSubmatch(output, -1) {
+		failed, _ := strconv.Atoi(matches[1])
+		passed, _ := strconv.Atoi(matches[2])
+		skipped, _ := strconv.Atoi(matches[3])
+		total, _ := strconv.Atoi(matches[4])
+
+		counts.Failed += failed
+		counts.Passed += passed
+		counts.Skipped += skipped
+		counts.Total += total
+	}
+
+	return counts
+}
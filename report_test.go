@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReport(t *testing.T) {
+
+	t.Run("RecordsCommandsAndResolvedValues", func(t *testing.T) {
+		cfg := newTestConfig(t, "webservice")
+		cfg.Action = "publish"
+		cfg.BuildVersion = "1.2.3"
+		cfg.Report = NewReport(cfg)
+		runner := &FakeCommandRunner{}
+
+		err := runAction(context.Background(), runner, cfg)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(cfg.Report.Commands) != 1 || cfg.Report.Commands[0].Command != runner.Commands[0].String() || cfg.Report.Commands[0].ExitCode != 0 {
+			t.Errorf("unexpected commands %+v", cfg.Report.Commands)
+		}
+		if cfg.Report.Values["publishProject"] != "src/Acme.FooApi.WebService" {
+			t.Errorf("unexpected publish project %v", cfg.Report.Values["publishProject"])
+		}
+		outputFolder := filepath.Join(cfg.WorkingDirectory, "publish")
+		if cfg.Report.Values["outputFolder"] != outputFolder || len(cfg.Report.Artifacts) != 1 || cfg.Report.Artifacts[0] != outputFolder {
+			t.Errorf("unexpected output folder %v and artifacts %v", cfg.Report.Values["outputFolder"], cfg.Report.Artifacts)
+		}
+	})
+
+	t.Run("RecordsFailedCommands", func(t *testing.T) {
+		cfg := newTestConfig(t, "webservice")
+		cfg.Action = "build"
+		cfg.Report = NewReport(cfg)
+		runner := &FakeCommandRunner{RunFunc: func(command Command) error {
+			return errors.New("exit status 1")
+		}}
+
+		err := runAction(context.Background(), runner, cfg)
+		cfg.Report.finish(err)
+
+		if err == nil {
+			t.Fatal("expected the build to fail")
+		}
+		if cfg.Report.Status != "failed" || cfg.Report.Error != "exit status 1" {
+			t.Errorf("unexpected status %v with error %v", cfg.Report.Status, cfg.Report.Error)
+		}
+		if len(cfg.Report.Commands) != 1 || cfg.Report.Commands[0].ExitCode != -1 {
+			t.Errorf("unexpected commands %+v", cfg.Report.Commands)
+		}
+	})
+
+	t.Run("SumsTestCounts", func(t *testing.T) {
+		cfg := newTestConfig(t, "webservice")
+		cfg.Report = NewReport(cfg)
+		runner := &FakeCommandRunner{RunFunc: func(command Command) error {
+			_, err := command.Output.Write([]byte("Passed!  - Failed:     0, Passed:     5, Skipped:     1, Total:     6, Duration: 1 s - Acme.FooApi.Tests.dll (net8.0)\n"))
+			return err
+		}}
+
+		err := runTests(context.Background(), runner, cfg, "")
+
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := TestCounts{Total: 12, Passed: 10, Failed: 0, Skipped: 2}
+		if cfg.Report.Tests == nil || *cfg.Report.Tests != expected {
+			t.Errorf("expected test counts %+v, got %+v", expected, cfg.Report.Tests)
+		}
+	})
+
+	t.Run("HasReportPerSolution", func(t *testing.T) {
+		cfg := newTestConfig(t, "webservice")
+		cfg.Action = "build"
+		cfg.AllSolutions = true
+		cfg.WorkingDirectory = newMonorepo(t, "a/Acme.A.sln", "b/Acme.B.sln")
+		cfg.Report = NewReport(cfg)
+
+		err := runAction(context.Background(), &FakeCommandRunner{}, cfg)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(cfg.Report.Solutions) != 2 || len(cfg.Report.Commands) != 0 {
+			t.Fatalf("expected a report for both solutions, got %+v", cfg.Report)
+		}
+		for _, solution := range cfg.Report.Solutions {
+			if solution.Status != "succeeded" || solution.Solution == "" || len(solution.Commands) != 1 {
+				t.Errorf("unexpected solution report %+v", solution)
+			}
+		}
+	})
+}
+
+func TestReportWrite(t *testing.T) {
+	report := &Report{Action: "publish", Status: "succeeded", Solution: "Acme.FooApi", Version: "1.2.3"}
+	report.SetValue("publishProject", "src/Acme.FooApi.WebService")
+	report.AddArtifact("publish")
+	reportPath := filepath.Join(t.TempDir(), ".estafette", "dotnet-report.json")
+
+	err := report.Write(reportPath)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	content, err := os.ReadFile(reportPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var written Report
+	err = json.Unmarshal(content, &written)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if written.Action != "publish" || written.Values["publishProject"] != "src/Acme.FooApi.WebService" {
+		t.Errorf("unexpected report %+v", &written)
+	}
+
+	dotenv, err := os.ReadFile(strings.TrimSuffix(reportPath, ".json") + ".env")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `ESTAFETTE_DOTNET_ACTION='publish'
+ESTAFETTE_DOTNET_ARTIFACTS='publish'
+ESTAFETTE_DOTNET_PUBLISH_PROJECT='src/Acme.FooApi.WebService'
+ESTAFETTE_DOTNET_SOLUTION='Acme.FooApi'
+ESTAFETTE_DOTNET_STATUS='succeeded'
+ESTAFETTE_DOTNET_VERSION='1.2.3'
+`
+	if string(dotenv) != expected {
+		t.Errorf("unexpected dotenv file:\n%v", string(dotenv))
+	}
+}
+
+func TestReportDotenvQuotesForShell(t *testing.T) {
+	report := &Report{Action: "restore", Status: "failed"}
+	report.SetValue("missingPackages", "Acme.$HOME `id` it's")
+
+	dotenv := report.dotenv()
+
+	if !strings.Contains(dotenv, "ESTAFETTE_DOTNET_MISSING_PACKAGES='Acme.$HOME `id` it'\\''s'\n") {
+		t.Errorf("expected the value to be single quoted, got:\n%v", dotenv)
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"time"
+
+	foundation "github.com/estafette/estafette-foundation"
+	"github.com/rs/zerolog/log"
+)
+
+// transientError is a failed command which is likely to succeed when it's retried, like a restore from a feed which was briefly unavailable
+type transientError struct {
+	reason string
+	err    error
+}
+
+func (e *transientError) Error() string {
+	return e.err.Error()
+}
+
+func (e *transientError) Unwrap() error {
+	return e.err
+}
+
+var (
+	// packages or versions which don't exist, locked mode failures, and rejected credentials or pushes don't change by retrying
+	permanentNugetErrorRegex = regexp.MustCompile(`\bNU1101\b|\bNU1102\b|\bNU1103\b|\bNU1004\b|\b(401|403|409) \(`)
+	// unreachable sources, server errors and timeouts usually do
+	transientNugetErrorRegex = regexp.MustCompile(`(?i)\bNU1301\b|\bNU1801\b|\b5\d\d \([a-z ]+\)|timed out|\btimeout\b|connection reset|an error occurred while sending the request`)
+)
+
+// Returns the part of the output of a failed NuGet command which shows the failure is transient, or an empty string if it's permanent or unknown.
+// A permanent error wins, as retrying a restore of a package which doesn't exist only delays the failure.
+func classifyNugetError(output string) string {
+	if permanentNugetErrorRegex.MatchString(output) {
+		return ""
+	}
+
+	return transientNugetErrorRegex.FindString(output)
+}
+
+// Runs the command, and retries it with exponential backoff starting at retryDelay while it fails with a transient NuGet error, up to maxRetries times.
+// The description, like "Restoring the packages", is used in the logs of the retries.
+func runWithRetries(ctx context.Context, runner CommandRunner, cfg Config, description string, command Command) error {
+	var output bytes.Buffer
+	retried := command
+	retried.Output = &output
+	if command.Output != nil {
+		retried.Output = io.MultiWriter(command.Output, &output)
+	}
+
+	attempt := 0
+	err := foundation.Retry(func() error {
+		attempt++
+		output.Reset()
+
+		err := runner.Run(ctx, retried)
+		if err == nil || ctx.Err() != nil {
+			return err
+		}
+
+		reason := classifyNugetError(output.String())
+		if reason == "" {
+			return err
+		}
+		if attempt <= cfg.MaxRetries {
+			log.Warn().Msgf("%v failed on transient error %q, retrying in %v (retry %v of %v)...", description, reason, cfg.RetryDelay*(1<<(attempt-1)), attempt, cfg.MaxRetries)
+		}
+
+		return &transientError{reason: reason, err: err}
+	},
+		foundation.Attempts(uint(cfg.MaxRetries)+1),
+		foundation.DelayMillisecond(int(cfg.RetryDelay/time.Millisecond)),
+		foundation.ExponentialBackOff(),
+		foundation.LastErrorOnly(true),
+		func(c *foundation.RetryConfig) {
+			c.IsRetryableError = func(err error) bool {
+				var transient *transientError
+				return errors.As(err, &transient)
+			}
+		})
+
+	var transient *transientError
+	if errors.As(err, &transient) && attempt > 1 {
+		return fmt.Errorf("%v failed %v times on transient error %q: %w", description, attempt, transient.reason, transient.err)
+	}
+	if transient != nil {
+		return transient.err
+	}
+
+	return err
+}
+
+// Returns an error if the maxRetries or retryDelay label is negative, as a negative number of retries would make the command never run at all.
+func validateRetries(cfg Config) error {
+	if cfg.MaxRetries < 0 {
+		return fmt.Errorf("the maxRetries label can't be negative, but it's %v", cfg.MaxRetries)
+	}
+	if cfg.RetryDelay < 0 {
+		return fmt.Errorf("the retryDelay label can't be negative, but it's %v", cfg.RetryDelay)
+	}
+
+	return nil
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClassifyNugetError(t *testing.T) {
+	tests := []struct {
+		output    string
+		transient string
+	}{
+		{"error NU1301: Unable to load the service index for source https://nuget.acme.com/v3/index.json.", "NU1301"},
+		{"Response status code does not indicate success: 503 (Service Unavailable).", "503 (Service Unavailable)"},
+		{"The HTTP request to 'GET https://nuget.acme.com/v3/index.json' has timed out after 100000ms.", "timed out"},
+		{"error NU1101: Unable to find package Acme.Unknown. No packages exist with this id in source(s): nuget.org", ""},
+		{"error NU1301: Unable to load the service index.\nerror NU1102: Unable to find package Serilog with version (>= 9.0.0)", ""},
+		{"Response status code does not indicate success: 409 (Conflict - The feed already contains 'Acme.Lib 1.0.0'.).", ""},
+		{"error MSB1009: Project file does not exist.", ""},
+	}
+
+	for _, tt := range tests {
+		if actual := classifyNugetError(tt.output); actual != tt.transient {
+			t.Errorf("expected %q for %q, got %q", tt.transient, tt.output, actual)
+		}
+	}
+}
+
+func TestRunWithRetries(t *testing.T) {
+	newRunner := func(outputs ...string) *FakeCommandRunner {
+		attempt := 0
+		return &FakeCommandRunner{RunFunc: func(command Command) error {
+			attempt++
+			if attempt > len(outputs) {
+				return nil
+			}
+			fmt.Fprint(command.Output, outputs[attempt-1])
+			return errors.New("exit status 1")
+		}}
+	}
+	cfg := Config{MaxRetries: 2}
+	command := Command{Name: "dotnet", Args: []string{"restore"}}
+
+	t.Run("RetriesTransientErrors", func(t *testing.T) {
+		runner := newRunner("error NU1301: Unable to load the service index", "503 (Service Unavailable)")
+
+		err := runWithRetries(context.Background(), runner, cfg, "Restoring the packages", command)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(runner.Commands) != 3 {
+			t.Errorf("expected 3 attempts, got %v", len(runner.Commands))
+		}
+	})
+
+	t.Run("GivesUpAfterMaxRetries", func(t *testing.T) {
+		runner := newRunner("error NU1301", "error NU1301", "error NU1301", "error NU1301")
+
+		err := runWithRetries(context.Background(), runner, cfg, "Restoring the packages", command)
+
+		if err == nil || err.Error() != `Restoring the packages failed 3 times on transient error "NU1301": exit status 1` {
+			t.Fatalf("expected an error after 3 attempts, got %v", err)
+		}
+		if len(runner.Commands) != 3 {
+			t.Errorf("expected 3 attempts, got %v", len(runner.Commands))
+		}
+	})
+
+	t.Run("FailsFastOnPermanentErrors", func(t *testing.T) {
+		runner := newRunner("error NU1101: Unable to find package Acme.Unknown.")
+
+		err := runWithRetries(context.Background(), runner, cfg, "Restoring the packages", command)
+
+		if err == nil || err.Error() != "exit status 1" {
+			t.Fatalf("expected the error of the command, got %v", err)
+		}
+		if len(runner.Commands) != 1 {
+			t.Errorf("expected a single attempt, got %v", len(runner.Commands))
+		}
+	})
+
+	t.Run("KeepsOutputOfTheCommand", func(t *testing.T) {
+		runner := newRunner("error NU1301", "error NU1004: The packages lock file is inconsistent")
+		var output strings.Builder
+		command := command
+		command.Output = &output
+
+		err := runWithRetries(context.Background(), runner, cfg, "Restoring the packages", command)
+
+		if err == nil {
+			t.Fatal("expected an error")
+		}
+		if !strings.Contains(output.String(), "NU1004") {
+			t.Errorf("expected the output to be written to the command's output, got %v", output.String())
+		}
+	})
+}
+
+func TestValidateRetries(t *testing.T) {
+	for _, action := range []string{"restore", "push-nuget"} {
+		for _, tt := range []struct {
+			maxRetries int
+			retryDelay time.Duration
+		}{
+			{-1, time.Second},
+			{3, -time.Second},
+		} {
+			cfg := newTestConfig(t, "library")
+			cfg.Action = action
+			cfg.MaxRetries = tt.maxRetries
+			cfg.RetryDelay = tt.retryDelay
+			runner := &FakeCommandRunner{}
+
+			err := runAction(context.Background(), runner, cfg)
+
+			if err == nil || !strings.Contains(err.Error(), "can't be negative") {
+				t.Errorf("expected action %v to fail validation for maxRetries %v and retryDelay %v, got %v", action, tt.maxRetries, tt.retryDelay, err)
+			}
+			assertCommands(t, runner)
+		}
+	}
+}
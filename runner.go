@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Command is a single invocation of an external tool, usually dotnet, as executed by an action
+type Command struct {
+	Name string
+	Args []string
+	// Env holds extra environment variables in KEY=value form, added on top of the environment of this process
+	Env []string
+	Dir string
+	// Secrets are masked whenever the command is logged or printed
+	Secrets []string
+	// Output receives a copy of stdout and stderr when it's set, so an action can inspect what the command printed
+	Output io.Writer
+}
+
+// String returns the command line with all secrets masked, so it's safe to log.
+// It's prefixed with the extra environment variables, of which values containing a secret are masked completely.
+func (c Command) String() string {
+	parts := make([]string, 0, len(c.Env)+1)
+	for _, env := range c.Env {
+		name, value, _ := strings.Cut(env, "=")
+		if maskSecrets(value, c.Secrets) != value {
+			value = "********"
+		}
+		parts = append(parts, name+"="+value)
+	}
+	parts = append(parts, maskSecrets(strings.TrimSpace(c.Name+" "+strings.Join(c.Args, " ")), c.Secrets))
+
+	return strings.Join(parts, " ")
+}
+
+// CommandRunner executes the commands of an action; it's injected so actions can be tested without a dotnet SDK
+type CommandRunner interface {
+	Run(ctx context.Context, command Command) error
+}
+
+// NewCommandRunner returns a CommandRunner which executes commands as child processes
+func NewCommandRunner() CommandRunner {
+	return &execCommandRunner{}
+}
+
+type execCommandRunner struct{}
+
+func (r *execCommandRunner) Run(ctx context.Context, command Command) error {
+	log.Printf("> %v", command)
+
+	cmd := exec.CommandContext(ctx, command.Name, command.Args...)
+	cmd.Env = append(os.Environ(), command.Env...)
+	cmd.Dir = command.Dir
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if command.Output != nil {
+		cmd.Stdout = io.MultiWriter(os.Stdout, command.Output)
+		cmd.Stderr = io.MultiWriter(os.Stderr, command.Output)
+	}
+
+	return cmd.Run()
+}
+
+// FakeCommandRunner records the commands instead of executing them
+type FakeCommandRunner struct {
+	mutex    sync.Mutex
+	Commands []Command
+	// RunFunc optionally decides the outcome of a command and can write to its Output, if not set every command succeeds
+	RunFunc func(command Command) error
+}
+
+// Run records the command and returns the result of RunFunc
+func (r *FakeCommandRunner) Run(ctx context.Context, command Command) error {
+	r.mutex.Lock()
+	r.Commands = append(r.Commands, command)
+	r.mutex.Unlock()
+
+	if r.RunFunc != nil {
+		return r.RunFunc(command)
+	}
+
+	return nil
+}
+
+func maskSecrets(s string, secrets []string) string {
+	for _, secret := range secrets {
+		if secret != "" {
+			s = strings.ReplaceAll(s, secret, "********")
+		}
+	}
+
+	return s
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestCommandString(t *testing.T) {
+	command := Command{
+		Name:    "dotnet",
+		Args:    []string{"nuget", "push", "Acme.Lib.1.0.0.nupkg", "--api-key", "secret-key"},
+		Secrets: []string{"secret-key", ""},
+	}
+
+	actual := command.String()
+
+	if actual != "dotnet nuget push Acme.Lib.1.0.0.nupkg --api-key ********" {
+		t.Errorf("unexpected command string %v", actual)
+	}
+}
+
+func TestCommandStringMasksEnvironmentWithSecrets(t *testing.T) {
+	command := Command{
+		Name:    "dotnet",
+		Args:    []string{"restore"},
+		Env:     []string{"DOTNET_CLI_TELEMETRY_OPTOUT=1", "NuGetPackageSourceCredentials_acme=Username=acme;Password=secret-key"},
+		Secrets: []string{"secret-key"},
+	}
+
+	actual := command.String()
+
+	if actual != "DOTNET_CLI_TELEMETRY_OPTOUT=1 NuGetPackageSourceCredentials_acme=******** dotnet restore" {
+		t.Errorf("unexpected command string %v", actual)
+	}
+}
+
+func TestFakeCommandRunner(t *testing.T) {
+	failure := errors.New("exit status 1")
+	runner := &FakeCommandRunner{
+		RunFunc: func(command Command) error {
+			if command.Args[0] == "test" {
+				return failure
+			}
+			return nil
+		},
+	}
+
+	err := runner.Run(context.Background(), Command{Name: "dotnet", Args: []string{"build"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = runner.Run(context.Background(), Command{Name: "dotnet", Args: []string{"test"}})
+	if err != failure {
+		t.Errorf("expected the error of RunFunc, got %v", err)
+	}
+	if len(runner.Commands) != 2 {
+		t.Errorf("expected 2 recorded commands, got %v", len(runner.Commands))
+	}
+}
@@ -0,0 +1,337 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// the project type guid Visual Studio uses for solution folders
+const solutionFolderTypeGUID = "{2150E333-8FDC-42A3-9474-1A3956D46DE8}"
+
+// Solution is a parsed .sln, .slnx or .slnf file
+type Solution struct {
+	Name string
+	// Path is the path of the solution file
+	Path string
+	// Dir is the directory the project paths are relative to, which for a solution filter is the one of the filtered solution
+	Dir      string
+	Projects []SolutionProject
+}
+
+// SolutionProject is a project entry of a solution file
+type SolutionProject struct {
+	Name string
+	// Path is relative to the solution directory and always uses forward slashes
+	Path     string
+	TypeGUID string
+	GUID     string
+	// SolutionFolder is the path of the solution folders the project is nested in, like src/Services
+	SolutionFolder string
+}
+
+// IsSolutionFolder returns true if the entry is a virtual solution folder instead of an actual project
+func (p SolutionProject) IsSolutionFolder() bool {
+	return strings.EqualFold(p.TypeGUID, solutionFolderTypeGUID)
+}
+
+var (
+	solutionProjectRegex = regexp.MustCompile(`^Project\("(\{[0-9A-Fa-f-]+\})"\)\s*=\s*"([^"]*)"\s*,\s*"([^"]*)"\s*,\s*"(\{[0-9A-Fa-f-]+\})"`)
+	nestedProjectRegex   = regexp.MustCompile(`^(\{[0-9A-Fa-f-]+\})\s*=\s*(\{[0-9A-Fa-f-]+\})$`)
+)
+
+// ParseSolutionFile reads all the projects from a .sln file, including the solution folders they are nested in
+func ParseSolutionFile(solutionPath string) (*Solution, error) {
+	file, err := os.Open(solutionPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed opening solution file %v: %w", solutionPath, err)
+	}
+	defer file.Close()
+
+	solution := &Solution{
+		Name: strings.TrimSuffix(filepath.Base(solutionPath), filepath.Ext(solutionPath)),
+		Path: solutionPath,
+		Dir:  filepath.Dir(solutionPath),
+	}
+
+	parents := map[string]string{}
+	inNestedProjects := false
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		switch {
+		case strings.HasPrefix(line, "GlobalSection(NestedProjects)"):
+			inNestedProjects = true
+		case line == "EndGlobalSection":
+			inNestedProjects = false
+		case inNestedProjects:
+			if matches := nestedProjectRegex.FindStringSubmatch(line); matches != nil {
+				parents[strings.ToUpper(matches[1])] = strings.ToUpper(matches[2])
+			}
+		default:
+			if matches := solutionProjectRegex.FindStringSubmatch(line); matches != nil {
+				solution.Projects = append(solution.Projects, SolutionProject{
+					TypeGUID: strings.ToUpper(matches[1]),
+					Name:     matches[2],
+					Path:     strings.ReplaceAll(matches[3], `\`, "/"),
+					GUID:     strings.ToUpper(matches[4]),
+				})
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed reading solution file %v: %w", solutionPath, err)
+	}
+
+	names := map[string]string{}
+	for _, p := range solution.Projects {
+		names[p.GUID] = p.Name
+	}
+	for i, p := range solution.Projects {
+		var folders []string
+		// walk up the parents, the depth check protects against cycles in a corrupt file
+		for parent, ok := parents[p.GUID]; ok && len(folders) < len(parents); parent, ok = parents[parent] {
+			folders = append([]string{names[parent]}, folders...)
+		}
+		solution.Projects[i].SolutionFolder = strings.Join(folders, "/")
+	}
+
+	return solution, nil
+}
+
+// Returns the actual projects of the solution, leaving out the solution folders.
+func (s *Solution) getProjects() []SolutionProject {
+	var projects []SolutionProject
+	for _, p := range s.Projects {
+		if !p.IsSolutionFolder() {
+			projects = append(projects, p)
+		}
+	}
+
+	return projects
+}
+
+// Returns the project with the specified name, or nil if the solution doesn't contain it.
+func (s *Solution) getProjectByName(name string) *SolutionProject {
+	for _, p := range s.getProjects() {
+		if p.Name == name {
+			return &p
+		}
+	}
+
+	return nil
+}
+
+// Reads the project file of a project in the solution, or returns nil if it isn't a project type msbuild can read.
+func (s *Solution) readProjectFile(p SolutionProject) (*ProjectFile, error) {
+	if !isReadableProjectFile(p.Path) {
+		return nil, nil
+	}
+
+	return ReadProjectFile(filepath.Join(s.Dir, filepath.FromSlash(p.Path)))
+}
+
+// Returns the path of the project relative to the directory the commands run in, with forward slashes.
+func (s *Solution) getProjectPath(p SolutionProject, workingDir string) string {
+	return relativePath(workingDir, filepath.Join(s.Dir, filepath.FromSlash(p.Path)))
+}
+
+// Returns the path of the solution file relative to the directory the commands run in, with forward slashes.
+func (s *Solution) getRelativePath(workingDir string) string {
+	return relativePath(workingDir, s.Path)
+}
+
+func relativePath(base, target string) string {
+	if rel, err := filepath.Rel(base, target); err == nil {
+		return filepath.ToSlash(rel)
+	}
+
+	return filepath.ToSlash(target)
+}
+
+type solutionFilterJSON struct {
+	Solution struct {
+		Path     string   `json:"path"`
+		Projects []string `json:"projects"`
+	} `json:"solution"`
+}
+
+// ParseSolutionFilterFile reads a .slnf file and returns the solution it filters with only the projects of the filter
+func ParseSolutionFilterFile(filterPath string) (*Solution, error) {
+	content, err := os.ReadFile(filterPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed reading solution filter file %v: %w", filterPath, err)
+	}
+
+	var filter solutionFilterJSON
+	err = json.Unmarshal(content, &filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed parsing solution filter file %v: %w", filterPath, err)
+	}
+	if filter.Solution.Path == "" {
+		return nil, fmt.Errorf("the solution filter file %v doesn't specify the solution it filters", filterPath)
+	}
+
+	solution, err := parseSolution(filepath.Join(filepath.Dir(filterPath), filepath.FromSlash(strings.ReplaceAll(filter.Solution.Path, `\`, "/"))))
+	if err != nil {
+		return nil, err
+	}
+
+	included := map[string]bool{}
+	for _, projectPath := range filter.Solution.Projects {
+		included[strings.ToLower(strings.ReplaceAll(projectPath, `\`, "/"))] = true
+	}
+
+	var projects []SolutionProject
+	for _, p := range solution.Projects {
+		if p.IsSolutionFolder() || included[strings.ToLower(p.Path)] {
+			projects = append(projects, p)
+		}
+	}
+
+	return &Solution{
+		Name:     strings.TrimSuffix(filepath.Base(filterPath), filepath.Ext(filterPath)),
+		Path:     filterPath,
+		Dir:      solution.Dir,
+		Projects: projects,
+	}, nil
+}
+
+type solutionXML struct {
+	Folders []struct {
+		Name     string               `xml:"Name,attr"`
+		Projects []solutionProjectXML `xml:"Project"`
+	} `xml:"Folder"`
+	Projects []solutionProjectXML `xml:"Project"`
+}
+
+type solutionProjectXML struct {
+	Path string `xml:"Path,attr"`
+	Type string `xml:"Type,attr"`
+	ID   string `xml:"Id,attr"`
+}
+
+// ParseSolutionXMLFile reads all the projects from a .slnx file, including the solution folders they are nested in
+func ParseSolutionXMLFile(solutionPath string) (*Solution, error) {
+	content, err := os.ReadFile(solutionPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed reading solution file %v: %w", solutionPath, err)
+	}
+
+	var parsed solutionXML
+	err = xml.Unmarshal(content, &parsed)
+	if err != nil {
+		return nil, fmt.Errorf("failed parsing solution file %v: %w", solutionPath, err)
+	}
+
+	solution := &Solution{
+		Name: strings.TrimSuffix(filepath.Base(solutionPath), filepath.Ext(solutionPath)),
+		Path: solutionPath,
+		Dir:  filepath.Dir(solutionPath),
+	}
+
+	newProject := func(p solutionProjectXML, folder string) SolutionProject {
+		projectPath := strings.ReplaceAll(p.Path, `\`, "/")
+		return SolutionProject{
+			Name:           strings.TrimSuffix(path.Base(projectPath), path.Ext(projectPath)),
+			Path:           projectPath,
+			TypeGUID:       strings.ToUpper(p.Type),
+			GUID:           strings.ToUpper(p.ID),
+			SolutionFolder: folder,
+		}
+	}
+
+	for _, p := range parsed.Projects {
+		solution.Projects = append(solution.Projects, newProject(p, ""))
+	}
+
+	// folders are listed flat with their full path, like /src/Services/
+	for _, f := range parsed.Folders {
+		folder := strings.Trim(f.Name, "/")
+		solution.Projects = append(solution.Projects, SolutionProject{
+			Name:           path.Base(folder),
+			Path:           path.Base(folder),
+			TypeGUID:       solutionFolderTypeGUID,
+			SolutionFolder: strings.TrimSuffix(path.Dir(folder), "."),
+		})
+		for _, p := range f.Projects {
+			solution.Projects = append(solution.Projects, newProject(p, folder))
+		}
+	}
+
+	return solution, nil
+}
+
+// Parses a solution file in any of the supported formats.
+func parseSolution(solutionPath string) (*Solution, error) {
+	switch strings.ToLower(filepath.Ext(solutionPath)) {
+	case ".slnf":
+		return ParseSolutionFilterFile(solutionPath)
+	case ".slnx":
+		return ParseSolutionXMLFile(solutionPath)
+	default:
+		return ParseSolutionFile(solutionPath)
+	}
+}
+
+// Returns the solution in the directory, or nil if there's no solution file.
+// 1. If a solution is selected, we use the file with that path or name, with or without extension.
+// 2. Otherwise we use the only .sln or .slnx file in the directory, and fail if there are several; solution filters are only used when selected.
+func findSolution(dir string, selection string) (*Solution, error) {
+	if selection != "" {
+		selectedPath := selection
+		if !filepath.IsAbs(selectedPath) {
+			selectedPath = filepath.Join(dir, selectedPath)
+		}
+		if info, err := os.Stat(selectedPath); err == nil && !info.IsDir() {
+			return parseSolution(selectedPath)
+		}
+		for _, ext := range []string{".sln", ".slnx", ".slnf"} {
+			if info, err := os.Stat(selectedPath + ext); err == nil && !info.IsDir() {
+				return parseSolution(selectedPath + ext)
+			}
+		}
+
+		return nil, fmt.Errorf("the solution %v selected with the 'solution' label can not be found in %v", selection, dir)
+	}
+
+	candidates, err := listSolutionFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	switch len(candidates) {
+	case 0:
+		return nil, nil
+	case 1:
+		return parseSolution(filepath.Join(dir, candidates[0]))
+	default:
+		return nil, fmt.Errorf("found several solutions %v in %v, please select one with the 'solution' label", strings.Join(candidates, ", "), dir)
+	}
+}
+
+// Returns the names of the .sln and .slnx files in the directory.
+func listSolutionFiles(dir string) ([]string, error) {
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, f := range files {
+		ext := strings.ToLower(filepath.Ext(f.Name()))
+		if !f.IsDir() && (ext == ".sln" || ext == ".slnx") {
+			names = append(names, f.Name())
+		}
+	}
+
+	return names, nil
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseSolutionFile(t *testing.T) {
+	solution, err := ParseSolutionFile("testdata/nested/Acme.Shop.sln")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if solution.Name != "Acme.Shop" {
+		t.Errorf("expected solution name Acme.Shop, got %v", solution.Name)
+	}
+
+	expected := []SolutionProject{
+		{Name: "Acme.Shop", Path: "Acme.Shop/Acme.Shop.csproj", TypeGUID: "{FAE04EC0-301F-11D3-BF4B-00C04F79EFBC}", GUID: "{0A6B7D3A-8E9C-4D4F-8A6B-2C3D4E5F6A7B}", SolutionFolder: "src"},
+		{Name: "Acme.Shop.Api", Path: "services/Acme.Shop.Api/Acme.Shop.Api.csproj", TypeGUID: "{9A19103F-16F7-4668-BE54-9A1E7A4F7556}", GUID: "{1B7C8E4B-9F0D-4E5A-9B7C-3D4E5F6A7B8C}", SolutionFolder: "src/Services"},
+		{Name: "Acme.Shop.Api.Specs", Path: "tests/Acme.Shop.Api.Specs/Acme.Shop.Api.Specs.csproj", TypeGUID: "{9A19103F-16F7-4668-BE54-9A1E7A4F7556}", GUID: "{2C8D9F5C-0A1E-4F6B-8C8D-4E5F6A7B8C9D}", SolutionFolder: "tests"},
+	}
+	if actual := solution.getProjects(); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("unexpected projects\nexpected:\n%+v\nactual:\n%+v", expected, actual)
+	}
+
+	if len(solution.Projects) != 6 {
+		t.Errorf("expected 6 entries including the solution folders, got %v", len(solution.Projects))
+	}
+	if !solution.Projects[1].IsSolutionFolder() || solution.Projects[1].SolutionFolder != "src" {
+		t.Errorf("expected Services to be a solution folder nested in src, got %+v", solution.Projects[1])
+	}
+}
+
+func TestFindSolution(t *testing.T) {
+	solution, err := findSolution(t.TempDir(), "")
+
+	if err != nil || solution != nil {
+		t.Errorf("expected no solution and no error, got %v and %v", solution, err)
+	}
+}
+
+func TestFindSolutionSelection(t *testing.T) {
+
+	t.Run("FailsWhenSeveralSolutionsAreFound", func(t *testing.T) {
+		_, err := findSolution("testdata/multi", "")
+
+		if err == nil || !strings.Contains(err.Error(), "Acme.App.Tools.slnx, Acme.App.sln") {
+			t.Fatalf("expected an error listing both solutions, got %v", err)
+		}
+	})
+
+	t.Run("SelectsSolutionByName", func(t *testing.T) {
+		solution, err := findSolution("testdata/multi", "Acme.App")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if solution.Path != filepath.Join("testdata", "multi", "Acme.App.sln") || len(solution.getProjects()) != 2 {
+			t.Errorf("expected Acme.App.sln with 2 projects, got %v with %v", solution.Path, solution.getProjects())
+		}
+	})
+
+	t.Run("FailsWhenSelectedSolutionDoesNotExist", func(t *testing.T) {
+		_, err := findSolution("testdata/multi", "Acme.Other.sln")
+
+		if err == nil {
+			t.Fatal("expected an error")
+		}
+	})
+}
+
+func TestParseSolutionFilterFile(t *testing.T) {
+	solution, err := ParseSolutionFilterFile("testdata/multi/Acme.App.Web.slnf")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if solution.Name != "Acme.App.Web" || solution.Dir != filepath.Join("testdata", "multi") {
+		t.Errorf("unexpected solution %v in %v", solution.Name, solution.Dir)
+	}
+	projects := solution.getProjects()
+	if len(projects) != 1 || projects[0].Path != "src/Acme.App.WebService/Acme.App.WebService.csproj" {
+		t.Errorf("expected only the web service project, got %+v", projects)
+	}
+}
+
+func TestParseSolutionXMLFile(t *testing.T) {
+	solution, err := ParseSolutionXMLFile("testdata/multi/Acme.App.Tools.slnx")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []SolutionProject{
+		{Name: "Acme.App.Tools", Path: "tools/Acme.App.Tools/Acme.App.Tools.csproj", SolutionFolder: "tools"},
+		{Name: "Acme.App.Tools.Tests", Path: "test/Acme.App.Tools.Tests/Acme.App.Tools.Tests.csproj", SolutionFolder: "tools/tests"},
+	}
+	if actual := solution.getProjects(); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("unexpected projects\nexpected:\n%+v\nactual:\n%+v", expected, actual)
+	}
+}
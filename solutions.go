@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	foundation "github.com/estafette/estafette-foundation"
+	"github.com/rs/zerolog/log"
+)
+
+// directories which never contain solutions of the repository itself
+var skippedDirectories = []string{"bin", "obj", "node_modules", "packages", "publish"}
+
+// Returns every .sln and .slnx solution under the root directory, in the order of their paths.
+func discoverSolutions(root string) ([]*Solution, error) {
+	var solutions []*Solution
+
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if path != root && (strings.HasPrefix(d.Name(), ".") || foundation.StringArrayContains(skippedDirectories, d.Name())) {
+			return filepath.SkipDir
+		}
+
+		names, err := listSolutionFiles(path)
+		if err != nil {
+			return err
+		}
+		for _, name := range names {
+			solution, err := parseSolution(filepath.Join(path, name))
+			if err != nil {
+				return err
+			}
+			solutions = append(solutions, solution)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed discovering the solutions under %v: %w", root, err)
+	}
+
+	return solutions, nil
+}
+
+// Runs the action for every solution under the working directory, with at most cfg.Parallelism solutions at the same time.
+// A failing solution doesn't stop the others, the error lists all solutions which failed.
+func runActionForAllSolutions(ctx context.Context, runner CommandRunner, cfg Config) error {
+	solutions, err := discoverSolutions(cfg.WorkingDirectory)
+	if err != nil {
+		return err
+	}
+	if len(solutions) == 0 {
+		return fmt.Errorf("no solutions were found under %v", cfg.WorkingDirectory)
+	}
+
+	parallelism := cfg.Parallelism
+	if parallelism < 1 {
+		parallelism = 1
+	}
+
+	// the report is written relative to the top-level working directory, so actions reading the report of an earlier stage have to find it there instead of in the directory of their solution
+	if cfg.ReportPath != "" && !filepath.IsAbs(cfg.ReportPath) {
+		cfg.ReportPath = filepath.Join(cfg.WorkingDirectory, cfg.ReportPath)
+	}
+
+	results := make([]stepResult, len(solutions))
+	semaphore := foundation.NewSemaphore(parallelism)
+	var wg sync.WaitGroup
+
+	for i, solution := range solutions {
+		solutionCfg := cfg
+		solutionCfg.WorkingDirectory = filepath.Dir(solution.Path)
+		solutionCfg.Solution = solution
+		solutionCfg.SolutionName = solution.Name
+		if cfg.Report != nil {
+			solutionCfg.Report = NewReport(solutionCfg)
+			cfg.Report.addSolution(solutionCfg.Report)
+		}
+
+		name := relativePath(cfg.WorkingDirectory, solution.Path)
+		log.Info().Msgf("Running action %v for solution %v...", cfg.Action, name)
+
+		semaphore.Acquire()
+		wg.Add(1)
+		go func(i int, name string, solutionCfg Config) {
+			defer wg.Done()
+			defer semaphore.Release()
+
+			start := time.Now()
+			err := runActionForSolution(ctx, runner, solutionCfg)
+			solutionCfg.Report.finish(err)
+			results[i] = stepResult{name: name, status: "succeeded", duration: time.Since(start), err: err}
+			if err != nil {
+				results[i].status = "failed"
+				log.Error().Err(err).Msgf("Action %v failed for solution %v.", cfg.Action, name)
+			}
+		}(i, name, solutionCfg)
+	}
+
+	wg.Wait()
+
+	log.Info().Msg(formatStepResults(fmt.Sprintf("Summary of action %v per solution:", cfg.Action), results))
+
+	var failed []string
+	for _, r := range results {
+		if r.err != nil {
+			failed = append(failed, r.name)
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("the action failed for solution(s) %v", strings.Join(failed, ", "))
+	}
+
+	return nil
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func newMonorepo(t *testing.T, solutionPaths ...string) string {
+	t.Helper()
+
+	root := t.TempDir()
+	for _, p := range solutionPaths {
+		solutionPath := filepath.Join(root, filepath.FromSlash(p))
+		err := os.MkdirAll(filepath.Dir(solutionPath), 0755)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = os.WriteFile(solutionPath, []byte("Microsoft Visual Studio Solution File, Format Version 12.00\n"), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return root
+}
+
+func TestDiscoverSolutions(t *testing.T) {
+	root := newMonorepo(t, "services/Acme.Orders/Acme.Orders.sln", "services/Acme.Payments/Acme.Payments.sln", "services/Acme.Payments/Acme.Payments.Tools.sln", "libs/Acme.Common/Acme.Common.sln", "services/Acme.Orders/bin/Release/Acme.Orders.sln", ".git/Acme.Hidden.sln")
+
+	solutions, err := discoverSolutions(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var actual []string
+	for _, s := range solutions {
+		actual = append(actual, relativePath(root, s.Path))
+	}
+	expected := []string{"libs/Acme.Common/Acme.Common.sln", "services/Acme.Orders/Acme.Orders.sln", "services/Acme.Payments/Acme.Payments.Tools.sln", "services/Acme.Payments/Acme.Payments.sln"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("unexpected solutions\nexpected:\n%q\nactual:\n%q", expected, actual)
+	}
+}
+
+func TestRunActionForAllSolutions(t *testing.T) {
+
+	t.Run("RunsActionInEverySolutionDirectory", func(t *testing.T) {
+		cfg := newTestConfig(t, "webservice")
+		cfg.Action = "build"
+		cfg.AllSolutions = true
+		cfg.Parallelism = 2
+		cfg.WorkingDirectory = newMonorepo(t, "services/Acme.Orders/Acme.Orders.sln", "libs/Acme.Common/Acme.Common.sln")
+		runner := &FakeCommandRunner{}
+
+		err := runAction(context.Background(), runner, cfg)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+		dirs := map[string]string{}
+		for _, c := range runner.Commands {
+			dirs[relativePath(cfg.WorkingDirectory, c.Dir)] = c.String()
+		}
+		expected := map[string]string{
+			"libs/Acme.Common":     "dotnet build Acme.Common.sln --configuration Release /p:IncludeSourceRevisionInInformationalVersion=false --no-restore",
+			"services/Acme.Orders": "dotnet build Acme.Orders.sln --configuration Release /p:IncludeSourceRevisionInInformationalVersion=false --no-restore",
+		}
+		if !reflect.DeepEqual(dirs, expected) {
+			t.Errorf("unexpected commands per directory\nexpected:\n%v\nactual:\n%v", expected, dirs)
+		}
+	})
+
+	t.Run("ContinuesAfterFailingSolution", func(t *testing.T) {
+		cfg := newTestConfig(t, "webservice")
+		cfg.Action = "build"
+		cfg.AllSolutions = true
+		cfg.WorkingDirectory = newMonorepo(t, "a/Acme.A.sln", "b/Acme.B.sln", "c/Acme.C.sln")
+		runner := &FakeCommandRunner{
+			RunFunc: func(command Command) error {
+				if strings.Contains(command.String(), "Acme.B.sln") {
+					return errors.New("exit status 1")
+				}
+				return nil
+			},
+		}
+
+		err := runAction(context.Background(), runner, cfg)
+
+		if err == nil || err.Error() != "the action failed for solution(s) b/Acme.B.sln" {
+			t.Fatalf("expected only solution b to fail, got %v", err)
+		}
+		if len(runner.Commands) != 3 {
+			t.Errorf("expected the action to run for all 3 solutions, got %q", commandLines(runner))
+		}
+	})
+
+	t.Run("FailsWithoutSolutions", func(t *testing.T) {
+		cfg := newTestConfig(t, "webservice")
+		cfg.Action = "build"
+		cfg.AllSolutions = true
+		cfg.WorkingDirectory = t.TempDir()
+
+		err := runAction(context.Background(), &FakeCommandRunner{}, cfg)
+
+		if err == nil {
+			t.Fatal("expected an error")
+		}
+	})
+}